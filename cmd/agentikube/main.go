@@ -1,39 +1,83 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/rathi/agentikube/internal/commands"
+	"github.com/rathi/agentikube/internal/kube"
 	"github.com/spf13/cobra"
 )
 
 var version = "dev"
 
 func main() {
+	os.Exit(run(newRootCmd(), os.Args[1:], os.Stderr))
+}
+
+func newRootCmd() *cobra.Command {
 	rootCmd := &cobra.Command{
 		Use:   "agentikube",
 		Short: "CLI for long-running agent sandboxes on Kubernetes",
 		Long:  "agentikube provisions and manages long-running agent sandboxes on AWS using Kubernetes.",
+
+		// run prints errors itself, once, and stays quiet for remote exit
+		// statuses.
+		SilenceErrors: true,
+		SilenceUsage:  true,
 	}
 
 	rootCmd.PersistentFlags().String("config", "agentikube.yaml", "path to config file")
+	rootCmd.PersistentFlags().StringP("output", "o", "", "output format for list, status and create: json, yaml, wide or name")
 
 	rootCmd.AddCommand(
 		commands.NewInitCmd(),
 		commands.NewUpCmd(),
+		commands.NewPlanCmd(),
 		commands.NewCreateCmd(),
+		commands.NewCloneCmd(),
+		commands.NewSecretsCmd(),
 		commands.NewListCmd(),
 		commands.NewSSHCmd(),
+		commands.NewSSHProxyCmd(),
+		commands.NewSSHConfigCmd(),
+		commands.NewExecCmd(),
+		commands.NewPortForwardCmd(),
+		commands.NewLogsCmd(),
+		commands.NewCpCmd(),
+		commands.NewSnapshotCmd(),
+		commands.NewRestoreCmd(),
+		commands.NewPauseCmd(),
+		commands.NewResumeCmd(),
+		commands.NewReaperCmd(),
 		commands.NewDownCmd(),
 		commands.NewDestroyCmd(),
 		commands.NewStatusCmd(),
+		commands.NewDoctorCmd(),
+		commands.NewEventsCmd(),
+		commands.NewTopCmd(),
+		commands.NewCostCmd(),
 	)
 
 	rootCmd.Version = version
 
+	return rootCmd
+}
+
+// run executes rootCmd with args and returns the process exit status.
+func run(rootCmd *cobra.Command, args []string, stderr io.Writer) int {
+	rootCmd.SetArgs(args)
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		// Remote commands exit with their own status and have already
+		// written their output.
+		var exitErr *kube.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.Code
+		}
+		fmt.Fprintln(stderr, err)
+		return 1
 	}
+	return 0
 }
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rathi/agentikube/internal/kube"
+	"github.com/spf13/cobra"
+)
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		runE    error
+		code    int
+		message string
+	}{
+		{name: "success", args: []string{"probe"}},
+		{name: "command error", args: []string{"probe"}, runE: errors.New("boom"), code: 1, message: "boom"},
+		{name: "exit status", args: []string{"probe"}, runE: &kube.ExitError{Code: 3}, code: 3},
+		{name: "wrapped exit status", args: []string{"probe"}, runE: fmt.Errorf("running in sandbox %q: %w", "demo", &kube.ExitError{Code: 3}), code: 3},
+		{name: "unknown command", args: []string{"nope"}, code: 1, message: `unknown command "nope"`},
+		{name: "unknown flag", args: []string{"probe", "--nope"}, code: 1, message: "unknown flag: --nope"},
+		{name: "destroy without handles", args: []string{"destroy"}, code: 1, message: "specify handles, --selector or --all"},
+	}
+	for _, tt := range tests {
+		root := newRootCmd()
+		root.AddCommand(&cobra.Command{
+			Use:  "probe",
+			RunE: func(*cobra.Command, []string) error { return tt.runE },
+		})
+		var stdout, stderr bytes.Buffer
+		root.SetOut(&stdout)
+		root.SetErr(&stderr)
+
+		code := run(root, tt.args, &stderr)
+		if code != tt.code {
+			t.Errorf("%s: exit status %d, want %d", tt.name, code, tt.code)
+		}
+		if stdout.Len() != 0 {
+			t.Errorf("%s: wrote to stdout: %q", tt.name, stdout.String())
+		}
+		if tt.message == "" {
+			if stderr.Len() != 0 {
+				t.Errorf("%s: wrote to stderr: %q", tt.name, stderr.String())
+			}
+			continue
+		}
+		if n := strings.Count(stderr.String(), tt.message); n != 1 {
+			t.Errorf("%s: stderr has the error %d times, want once:\n%s", tt.name, n, stderr.String())
+		}
+	}
+}
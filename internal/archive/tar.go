@@ -0,0 +1,174 @@
+package archive
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Owner is the numeric owner recorded on every archived entry. Sandboxes run
+// as a fixed non-root user, so local ownership is replaced rather than kept.
+type Owner struct {
+	UID int
+	GID int
+}
+
+// Write archives the file or directory at src into w. Entries are named
+// under prefix (slash-separated); the root of src becomes prefix itself.
+// File modes are preserved.
+func Write(w io.Writer, src, prefix string, owner Owner) error {
+	tw := tar.NewWriter(w)
+
+	err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		name := prefix
+		if rel != "." {
+			name = path.Join(prefix, filepath.ToSlash(rel))
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		var link string
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return fmt.Errorf("archiving %s: %w", p, err)
+		}
+		hdr.Name = name
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		hdr.Uid, hdr.Gid = owner.UID, owner.GID
+		hdr.Uname, hdr.Gname = "", ""
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
+// Size returns the total size in bytes of the regular files under src. It is
+// used to estimate transfer progress.
+func Size(src string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(src, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
+
+// Extract unpacks the tar stream r into dst. Only entries at or below prefix
+// are extracted, with prefix replaced by dst. Links are not followed or
+// created; their names are returned as skipped. Entries that would escape dst
+// are rejected.
+func Extract(r io.Reader, dst, prefix string) (skipped []string, err error) {
+	tr := tar.NewReader(r)
+	prefix = path.Clean(prefix)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return skipped, nil
+		}
+		if err != nil {
+			return skipped, fmt.Errorf("reading archive: %w", err)
+		}
+
+		name := path.Clean(hdr.Name)
+		var rel string
+		switch {
+		case prefix == ".":
+			rel = name
+		case name == prefix:
+			rel = "."
+		case strings.HasPrefix(name, prefix+"/"):
+			rel = strings.TrimPrefix(name, prefix+"/")
+		default:
+			continue
+		}
+
+		target := filepath.Join(dst, filepath.FromSlash(rel))
+		if target != filepath.Clean(dst) && !strings.HasPrefix(target, filepath.Clean(dst)+string(filepath.Separator)) {
+			return skipped, fmt.Errorf("archive entry %q escapes destination", hdr.Name)
+		}
+
+		mode := hdr.FileInfo().Mode().Perm()
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, mode|0o700); err != nil {
+				return skipped, err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return skipped, err
+			}
+			if err := writeFile(target, tr, mode); err != nil {
+				return skipped, err
+			}
+		default:
+			skipped = append(skipped, hdr.Name)
+		}
+	}
+}
+
+func writeFile(target string, r io.Reader, mode fs.FileMode) error {
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return fmt.Errorf("writing %s: %w", target, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	// OpenFile only applies mode on creation and is subject to umask.
+	return os.Chmod(target, mode)
+}
@@ -0,0 +1,142 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// entry is one tar header; regular files get content as their body.
+type entry struct {
+	name     string
+	typeflag byte
+	linkname string
+	content  string
+}
+
+func tarball(t *testing.T, entries ...entry) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typeflag, Linkname: e.linkname, Mode: 0o644}
+		if e.typeflag == tar.TypeReg {
+			hdr.Size = int64(len(e.content))
+		}
+		if e.typeflag == tar.TypeDir {
+			hdr.Mode = 0o755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func assertMissing(t *testing.T, p string) {
+	t.Helper()
+	if _, err := os.Lstat(p); !os.IsNotExist(err) {
+		t.Errorf("%s exists outside the destination (err %v)", p, err)
+	}
+}
+
+func assertContent(t *testing.T, p, want string) {
+	t.Helper()
+	data, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Errorf("%s = %q, want %q", p, data, want)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	dst := t.TempDir()
+	skipped, err := Extract(tarball(t,
+		entry{name: "repo/", typeflag: tar.TypeDir},
+		entry{name: "repo/src/main.go", typeflag: tar.TypeReg, content: "package main"},
+		entry{name: "repox/other", typeflag: tar.TypeReg, content: "not under the prefix"},
+	), dst, "repo")
+	if err != nil || len(skipped) != 0 {
+		t.Fatalf("Extract = %v, %v", skipped, err)
+	}
+	assertContent(t, filepath.Join(dst, "src", "main.go"), "package main")
+	assertMissing(t, filepath.Join(dst, "other"))
+}
+
+func TestExtractParentTraversal(t *testing.T) {
+	root := t.TempDir()
+	dst := filepath.Join(root, "dst")
+
+	_, err := Extract(tarball(t, entry{name: "../x", typeflag: tar.TypeReg, content: "escaped"}), dst, ".")
+	if err == nil {
+		t.Fatal("Extract of ../x succeeded, want an error")
+	}
+	assertMissing(t, filepath.Join(root, "x"))
+
+	// Under a prefix, ../ entries no longer match it and are ignored.
+	_, err = Extract(tarball(t, entry{name: "repo/../../x", typeflag: tar.TypeReg, content: "escaped"}), dst, "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMissing(t, filepath.Join(root, "x"))
+}
+
+func TestExtractAbsolutePath(t *testing.T) {
+	root := t.TempDir()
+	dst := filepath.Join(root, "dst")
+	outside := filepath.Join(root, "etc", "x")
+
+	_, err := Extract(tarball(t, entry{name: filepath.ToSlash(outside), typeflag: tar.TypeReg, content: "absolute"}), dst, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMissing(t, outside)
+	assertContent(t, filepath.Join(dst, outside), "absolute")
+}
+
+func TestExtractSymlinkWrite(t *testing.T) {
+	root := t.TempDir()
+	dst := filepath.Join(root, "dst")
+	outside := filepath.Join(root, "outside")
+	if err := os.Mkdir(outside, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	skipped, err := Extract(tarball(t,
+		entry{name: "abs", typeflag: tar.TypeSymlink, linkname: outside},
+		entry{name: "abs/x", typeflag: tar.TypeReg, content: "through abs"},
+		entry{name: "up", typeflag: tar.TypeSymlink, linkname: ".."},
+		entry{name: "up/x", typeflag: tar.TypeReg, content: "through up"},
+		entry{name: "hard", typeflag: tar.TypeLink, linkname: "abs/x"},
+	), dst, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"abs", "up", "hard"}; !slices.Equal(skipped, want) {
+		t.Errorf("skipped = %v, want %v", skipped, want)
+	}
+
+	assertMissing(t, filepath.Join(outside, "x"))
+	assertMissing(t, filepath.Join(root, "x"))
+	for _, link := range []string{"abs", "up"} {
+		info, err := os.Lstat(filepath.Join(dst, link))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.IsDir() {
+			t.Errorf("%s was extracted as %v, want a plain directory", link, info.Mode())
+		}
+	}
+	assertContent(t, filepath.Join(dst, "up", "x"), "through up")
+}
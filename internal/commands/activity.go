@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rathi/agentikube/internal/kube"
+)
+
+// activityInterval is how often long-running sessions refresh the activity
+// annotation. It must stay well below the smallest useful idle window.
+const activityInterval = time.Minute
+
+// keepActive marks the sandbox as in use now and then every activityInterval
+// until ctx is done. Failures are ignored: activity tracking must never break
+// the session itself.
+func keepActive(ctx context.Context, client *kube.Client, namespace, handle string) {
+	touch := func() {
+		annotateClaim(ctx, client, namespace, sandboxName(handle), map[string]interface{}{
+			activityAnnotation: time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+
+	touch()
+	go func() {
+		for sleepCtx(ctx, activityInterval) {
+			touch()
+		}
+	}()
+}
+
+// wakeIfHibernated resumes a sandbox that the reaper hibernated and waits for
+// it to become ready. Sandboxes paused by a user are left alone. trigger names
+// the command that woke it and is recorded on the claim.
+func wakeIfHibernated(ctx context.Context, client *kube.Client, namespace, handle, trigger string) error {
+	claim, err := getClaim(ctx, client, namespace, handle)
+	if err != nil {
+		return err
+	}
+	if !isPaused(claim.Object) || claimAnnotation(claim.Object, actionAnnotation) != actionHibernate {
+		return nil
+	}
+
+	// Status goes to stderr so it never mixes with command output or an
+	// ssh-proxy byte stream.
+	fmt.Fprintf(os.Stderr, "sandbox %q is hibernated, waking it up...\n", handle)
+	old, _, err := resumeSandbox(ctx, client, namespace, handle, actionWake, "woken by "+trigger)
+	if err != nil {
+		return err
+	}
+	if _, err := waitForNewPod(ctx, client, namespace, handle, old, defaultReadyTimeout); err != nil {
+		return err
+	}
+	if err := syncPodMetadata(ctx, client, namespace, handle); err != nil {
+		fmt.Fprintf(os.Stderr, "[warn] %v\n", err)
+	}
+	fmt.Fprintf(os.Stderr, "[ok] sandbox %q is awake\n", handle)
+	return nil
+}
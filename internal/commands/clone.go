@@ -0,0 +1,218 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rathi/agentikube/internal/config"
+	"github.com/rathi/agentikube/internal/kube"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// freezeScript sends a signal to every process in the container except the
+// shell running it. PID 1 ignores SIGSTOP, so only its children are frozen.
+const freezeScript = `for d in /proc/[0-9]*; do p=${d#/proc/}; [ "$p" = "$$" ] || kill -"$1" "$p" 2>/dev/null; done; true`
+
+func NewCloneCmd() *cobra.Command {
+	var provider string
+	var apiKey apiKeyFlags
+	var quiesce bool
+
+	cmd := &cobra.Command{
+		Use:   "clone <src> <dst>",
+		Short: "Fork a sandbox into a new handle",
+		Long: "Creates a new sandbox for <dst> and copies the workspace of <src> into it. The\n" +
+			"source's labels and annotations are copied, and its provider credentials are\n" +
+			"reused unless overridden. A paused source is resumed for the copy and paused\n" +
+			"again afterwards. Copying from a running source can capture files mid-write;\n" +
+			"--quiesce freezes the source's processes (except PID 1) for the duration of\n" +
+			"the copy.",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			src, dst := args[0], args[1]
+			if err := validateHandle(dst); err != nil {
+				return err
+			}
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			client, err := kube.NewClient()
+			if err != nil {
+				return fmt.Errorf("connecting to cluster: %w", err)
+			}
+
+			ns := cfg.Namespace
+
+			srcClaim, err := getClaim(ctx, client, ns, src)
+			if err != nil {
+				return err
+			}
+			srcName := srcClaim.GetName()
+
+			srcSecret, err := client.Clientset().CoreV1().Secrets(ns).Get(ctx, srcName, metav1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("getting Secret %q: %w", srcName, err)
+			}
+
+			secretData := make(map[string]string, len(srcSecret.Data))
+			for k, v := range srcSecret.Data {
+				secretData[k] = string(v)
+			}
+			secretData["USER_NAME"] = dst
+			if cmd.Flags().Changed("provider") {
+				secretData["PROVIDER"] = provider
+			}
+			key, ok, err := apiKey.read(cmd)
+			if err != nil {
+				return err
+			}
+			if ok {
+				secretData["PROVIDER_KEY"] = key
+			}
+
+			existing, err := getSandboxObjects(ctx, client, ns, dst)
+			if err != nil {
+				return err
+			}
+			if err := existing.check(dst, createNew); err != nil {
+				return err
+			}
+
+			txn, err := createSandbox(ctx, client, os.Stdout, ns, dst, createNew, existing, claimMetadata(srcClaim), secretData)
+			if err != nil {
+				return err
+			}
+			// From here on a failure removes the half-made clone.
+			fail := func(err error) error {
+				txn.rollback(os.Stdout)
+				return err
+			}
+			if err := waitSandboxReady(ctx, client, os.Stdout, ns, dst, defaultReadyTimeout); err != nil {
+				return fail(err)
+			}
+			if err := syncPodMetadata(ctx, client, ns, dst); err != nil {
+				fmt.Printf("[warn] %v\n", err)
+			}
+
+			dstPod, err := resolvePod(ctx, client, ns, dst)
+			if err != nil {
+				return fail(err)
+			}
+
+			var srcPod string
+			if isPaused(srcClaim.Object) {
+				// Put the source back the way it was: a hibernated sandbox
+				// must stay eligible for wake-on-use.
+				pauseAction := actionPause
+				if claimAnnotation(srcClaim.Object, actionAnnotation) == actionHibernate {
+					pauseAction = actionHibernate
+				}
+
+				fmt.Printf("source sandbox %q is paused, resuming it for the copy...\n", src)
+				old, _, err := resumeSandbox(ctx, client, ns, src, actionResume, "resumed to clone into "+dst)
+				if err != nil {
+					return fail(err)
+				}
+				defer func() {
+					if _, err := pauseSandbox(ctx, client, ns, src, pauseAction, "paused again after clone into "+dst); err != nil {
+						fmt.Printf("[warn] could not pause source sandbox %q again: %v\n", src, err)
+						return
+					}
+					fmt.Printf("[ok] source sandbox %q paused again\n", src)
+				}()
+				fmt.Println("waiting for source sandbox to be ready...")
+				srcPod, err = waitForNewPod(ctx, client, ns, src, old, defaultReadyTimeout)
+				if err != nil {
+					return fail(err)
+				}
+			} else {
+				srcPod, err = resolvePod(ctx, client, ns, src)
+				if err != nil {
+					return fail(err)
+				}
+			}
+
+			if quiesce {
+				if err := signalPod(ctx, client, ns, srcPod, "STOP"); err != nil {
+					return fail(fmt.Errorf("freezing source sandbox: %w", err))
+				}
+				fmt.Printf("[ok] source sandbox %q frozen\n", src)
+				defer func() {
+					if err := signalPod(ctx, client, ns, srcPod, "CONT"); err != nil {
+						fmt.Printf("[warn] could not unfreeze source sandbox %q: %v\n", src, err)
+						return
+					}
+					fmt.Printf("[ok] source sandbox %q unfrozen\n", src)
+				}()
+			} else if !isPaused(srcClaim.Object) {
+				fmt.Printf("[warn] source sandbox %q is running; files written during the copy may be inconsistent (use --quiesce)\n", src)
+			}
+
+			if err := copyWorkspace(ctx, client, cfg, srcPod, dstPod); err != nil {
+				return fail(err)
+			}
+			fmt.Printf("[ok] workspace copied from %q\n", src)
+
+			printSandboxReady(ns, dst)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&provider, "provider", "", "LLM provider name (default: the source's provider)")
+	apiKey.register(cmd, "LLM provider API key (default: the source's key)")
+	cmd.Flags().BoolVar(&quiesce, "quiesce", false, "freeze the source's processes while copying")
+
+	return cmd
+}
+
+// copyWorkspace streams the workspace of srcPod into the workspace of
+// dstPod without staging it locally.
+func copyWorkspace(ctx context.Context, client *kube.Client, cfg *config.Config, srcPod, dstPod string) error {
+	ns := cfg.Namespace
+	prog := newProgress("copying", 0)
+	pr, pw := io.Pipe()
+
+	done := make(chan error, 1)
+	go func() {
+		err := streamToPod(ctx, client, ns, dstPod, cfg.Sandbox.MountPath, pr)
+		pr.CloseWithError(io.ErrClosedPipe)
+		done <- err
+	}()
+
+	err := streamFromPod(ctx, client, ns, srcPod, cfg.Sandbox.MountPath, ".", io.MultiWriter(pw, prog))
+	pw.CloseWithError(err)
+	dstErr := <-done
+	prog.Done()
+
+	if err != nil {
+		return fmt.Errorf("reading source workspace: %w", err)
+	}
+	if dstErr != nil {
+		return fmt.Errorf("writing destination workspace: %w", dstErr)
+	}
+	return nil
+}
+
+// signalPod sends sig (e.g. STOP or CONT) to every process in podName except
+// PID 1 and the shell delivering it.
+func signalPod(ctx context.Context, client *kube.Client, namespace, podName, sig string) error {
+	var stderr bytes.Buffer
+	err := client.Exec(ctx, kube.ExecOptions{
+		Namespace: namespace,
+		Pod:       podName,
+		Command:   []string{"sh", "-c", freezeScript, "sh", sig},
+		Stderr:    &stderr,
+	})
+	if err != nil {
+		return remoteError(err, &stderr)
+	}
+	return nil
+}
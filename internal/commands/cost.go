@@ -0,0 +1,310 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/rathi/agentikube/internal/config"
+	"github.com/rathi/agentikube/internal/kube"
+	"github.com/rathi/agentikube/internal/pricing"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const gib = 1 << 30
+
+func NewCostCmd() *cobra.Command {
+	var pricesPath string
+
+	cmd := &cobra.Command{
+		Use:   "cost",
+		Short: "Estimate what the sandbox compute costs",
+		Long: "Estimates the hourly and monthly cost of the running Karpenter nodes, of each\n" +
+			"sandbox and of the warm pool, and what compute.maxCpu and compute.maxMemory\n" +
+			"would cost at full scale. Node prices come from an embedded us-east-1 price\n" +
+			"table; --prices lays a YAML file with the same layout over it.\n\n" +
+			"A sandbox is charged the share of its node given by the larger of its CPU and\n" +
+			"memory requests relative to the node's allocatable resources. On Fargate it is\n" +
+			"charged for its requests directly.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			prices, err := pricing.Load(pricesPath)
+			if err != nil {
+				return err
+			}
+
+			client, err := kube.NewClient()
+			if err != nil {
+				return fmt.Errorf("connecting to cluster: %w", err)
+			}
+
+			report, err := collectCost(ctx, client, cfg, prices)
+			if err != nil {
+				return err
+			}
+			printCost(os.Stdout, cfg, prices, report)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&pricesPath, "prices", "", "YAML price table overriding the embedded one")
+
+	return cmd
+}
+
+// nodeCost is the price of one Karpenter node.
+type nodeCost struct {
+	name         string
+	instanceType string
+	capacityType string
+	hourly       float64
+	err          error
+
+	cpu    resource.Quantity
+	memory resource.Quantity
+}
+
+// podCost is the share of compute cost attributed to one sandbox pod.
+type podCost struct {
+	handle string
+	pod    string
+	node   string
+	cpu    resource.Quantity
+	memory resource.Quantity
+	hourly float64
+}
+
+type costReport struct {
+	nodes     []nodeCost
+	sandboxes []podCost
+	warmPool  []podCost
+	warnings  []string
+}
+
+func collectCost(ctx context.Context, client *kube.Client, cfg *config.Config, prices *pricing.Table) (*costReport, error) {
+	ns := cfg.Namespace
+	report := &costReport{}
+	fargate := cfg.Compute.Type == "fargate"
+
+	nodes := map[string]*nodeCost{}
+	if !fargate {
+		list, err := client.Clientset().CoreV1().Nodes().List(ctx, metav1.ListOptions{
+			LabelSelector: "karpenter.sh/nodepool=" + nodePoolName,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("listing nodes: %w", err)
+		}
+		for _, node := range list.Items {
+			nc := nodeCost{
+				name:         node.Name,
+				instanceType: node.Labels["node.kubernetes.io/instance-type"],
+				capacityType: node.Labels["karpenter.sh/capacity-type"],
+				cpu:          node.Status.Allocatable[corev1.ResourceCPU],
+				memory:       node.Status.Allocatable[corev1.ResourceMemory],
+			}
+			nc.hourly, nc.err = prices.Hourly(nc.instanceType, nc.capacityType)
+			if nc.err != nil {
+				report.warnings = append(report.warnings, fmt.Sprintf("node %s: %v; add it with --prices", node.Name, nc.err))
+			}
+			report.nodes = append(report.nodes, nc)
+		}
+		sort.Slice(report.nodes, func(i, j int) bool { return report.nodes[i].name < report.nodes[j].name })
+		for i := range report.nodes {
+			nodes[report.nodes[i].name] = &report.nodes[i]
+		}
+	}
+
+	claims, err := client.Dynamic().Resource(sandboxClaimGVR).Namespace(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("listing SandboxClaims: %w", err)
+	}
+	handles := map[string]string{}
+	for _, claim := range claims.Items {
+		if pod := extractPodName(claim.Object); pod != "-" && !isPaused(claim.Object) {
+			handles[pod] = handleFromClaim(&claim)
+		}
+	}
+
+	pods, err := client.Clientset().CoreV1().Pods(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("listing pods: %w", err)
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		handle, claimed := handles[pod.Name]
+		if !claimed && !ownedBySandbox(pod.OwnerReferences, pod.Name) {
+			continue
+		}
+
+		requests, _ := podResources(pod)
+		pc := podCost{
+			handle: handle,
+			pod:    pod.Name,
+			node:   pod.Spec.NodeName,
+			cpu:    requests[corev1.ResourceCPU],
+			memory: requests[corev1.ResourceMemory],
+		}
+		if fargate {
+			pc.hourly = pc.cpu.AsApproximateFloat64()*prices.Fargate.VCPUHour +
+				pc.memory.AsApproximateFloat64()/gib*prices.Fargate.GBHour
+		} else if node, ok := nodes[pc.node]; ok {
+			pc.hourly = node.hourly * nodeShare(node, pc)
+		}
+
+		if claimed {
+			report.sandboxes = append(report.sandboxes, pc)
+		} else {
+			report.warmPool = append(report.warmPool, pc)
+		}
+	}
+	sort.Slice(report.sandboxes, func(i, j int) bool { return report.sandboxes[i].handle < report.sandboxes[j].handle })
+
+	return report, nil
+}
+
+// nodeShare is the fraction of a node a pod occupies: the larger of its
+// CPU and memory requests relative to what the node can allocate.
+func nodeShare(node *nodeCost, pc podCost) float64 {
+	var share float64
+	if !node.cpu.IsZero() {
+		share = float64(pc.cpu.MilliValue()) / float64(node.cpu.MilliValue())
+	}
+	if !node.memory.IsZero() {
+		share = max(share, pc.memory.AsApproximateFloat64()/node.memory.AsApproximateFloat64())
+	}
+	return min(share, 1)
+}
+
+// fullScale is the cost of running compute.maxCpu and compute.maxMemory on
+// one instance type and capacity type.
+type fullScale struct {
+	instanceType string
+	capacityType string
+	nodes        int
+	hourly       float64
+	err          error
+}
+
+// projectFullScale prices the NodePool limits for every configured instance
+// type and capacity type, cheapest first.
+func projectFullScale(cfg *config.Config, prices *pricing.Table) ([]fullScale, error) {
+	maxMemory, err := resource.ParseQuantity(cfg.Compute.MaxMemory)
+	if err != nil {
+		return nil, fmt.Errorf("parsing compute.maxMemory: %w", err)
+	}
+	memGiB := maxMemory.AsApproximateFloat64() / gib
+
+	var out []fullScale
+	for _, it := range cfg.Compute.InstanceTypes {
+		for _, ct := range cfg.Compute.CapacityTypes {
+			fs := fullScale{instanceType: it, capacityType: ct}
+			price, err := prices.Hourly(it, ct)
+			inst := prices.Instances[it]
+			if err != nil || inst.CPU <= 0 || inst.MemoryGiB <= 0 {
+				if err == nil {
+					err = fmt.Errorf("no size for instance type %q", it)
+				}
+				fs.err = err
+				out = append(out, fs)
+				continue
+			}
+			fs.nodes = int(math.Max(
+				math.Ceil(float64(cfg.Compute.MaxCPU)/inst.CPU),
+				math.Ceil(memGiB/inst.MemoryGiB),
+			))
+			fs.hourly = float64(fs.nodes) * price
+			out = append(out, fs)
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if (out[i].err == nil) != (out[j].err == nil) {
+			return out[i].err == nil
+		}
+		return out[i].hourly < out[j].hourly
+	})
+	return out, nil
+}
+
+func printCost(w io.Writer, cfg *config.Config, prices *pricing.Table, r *costReport) {
+	money := func(hourly float64) string {
+		return fmt.Sprintf("$%.3f\t$%.2f", hourly, prices.Monthly(hourly))
+	}
+
+	fmt.Fprintf(w, "prices: %s, USD, %.0f hours per month\n", prices.Region, prices.HoursPerMonth)
+	for _, warn := range r.warnings {
+		fmt.Fprintf(w, "[warn] %s\n", warn)
+	}
+
+	var nodesTotal float64
+	if cfg.Compute.Type != "fargate" {
+		fmt.Fprintln(w, "\nnodes:")
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "NODE\tINSTANCE TYPE\tCAPACITY\tHOURLY\tMONTHLY")
+		for _, n := range r.nodes {
+			price := money(n.hourly)
+			if n.err != nil {
+				price = "?\t?"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", n.name, dash(n.instanceType), dash(n.capacityType), price)
+			nodesTotal += n.hourly
+		}
+		fmt.Fprintf(tw, "total\t\t\t%s\n", money(nodesTotal))
+		tw.Flush()
+	}
+
+	fmt.Fprintln(w, "\nsandboxes:")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "HANDLE\tNODE\tCPU REQ\tMEM REQ\tHOURLY\tMONTHLY")
+	var sandboxesTotal, warmPoolTotal float64
+	for _, s := range r.sandboxes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", s.handle, dash(s.node), formatCPU(&s.cpu), formatMemory(&s.memory), money(s.hourly))
+		sandboxesTotal += s.hourly
+	}
+	for _, s := range r.warmPool {
+		warmPoolTotal += s.hourly
+	}
+	fmt.Fprintf(tw, "total (%d)\t\t\t\t%s\n", len(r.sandboxes), money(sandboxesTotal))
+	fmt.Fprintf(tw, "warm pool (%d)\t\t\t\t%s\n", len(r.warmPool), money(warmPoolTotal))
+	if cfg.Compute.Type != "fargate" {
+		unused := max(nodesTotal-sandboxesTotal-warmPoolTotal, 0)
+		fmt.Fprintf(tw, "other and unused\t\t\t\t%s\n", money(unused))
+	}
+	tw.Flush()
+
+	if cfg.Compute.Type != "karpenter" {
+		return
+	}
+	projection, err := projectFullScale(cfg, prices)
+	if err != nil {
+		fmt.Fprintf(w, "\n[warn] %v\n", err)
+		return
+	}
+	fmt.Fprintf(w, "\nat full scale (compute.maxCpu %d, compute.maxMemory %s):\n", cfg.Compute.MaxCPU, cfg.Compute.MaxMemory)
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "INSTANCE TYPE\tCAPACITY\tNODES\tHOURLY\tMONTHLY")
+	for _, p := range projection {
+		if p.err != nil {
+			fmt.Fprintf(tw, "%s\t%s\t-\t?\t?\n", p.instanceType, p.capacityType)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", p.instanceType, p.capacityType, p.nodes, money(p.hourly))
+	}
+	tw.Flush()
+}
@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/rathi/agentikube/internal/config"
+	"github.com/rathi/agentikube/internal/pricing"
+)
+
+func TestProjectFullScale(t *testing.T) {
+	prices := &pricing.Table{Instances: map[string]pricing.Instance{
+		"small":   {CPU: 4, MemoryGiB: 16, OnDemand: 0.2, Spot: 0.1},
+		"compute": {CPU: 8, MemoryGiB: 16, OnDemand: 0.3},
+		"unsized": {OnDemand: 1},
+	}}
+
+	tests := []struct {
+		name         string
+		maxCPU       int
+		maxMemory    string
+		instanceType string
+		nodes        int
+	}{
+		{"cpu bound", 40, "64Gi", "small", 10},
+		{"memory bound", 8, "160Gi", "small", 10},
+		{"rounds cpu up", 41, "16Gi", "small", 11},
+		{"rounds memory up", 4, "17Gi", "small", 2},
+		{"larger instance", 40, "64Gi", "compute", 5},
+		{"memory bound on larger instance", 40, "128Gi", "compute", 8},
+	}
+	for _, tt := range tests {
+		cfg := &config.Config{Compute: config.ComputeConfig{
+			MaxCPU:        tt.maxCPU,
+			MaxMemory:     tt.maxMemory,
+			InstanceTypes: []string{tt.instanceType},
+			CapacityTypes: []string{pricing.OnDemand},
+		}}
+		got, err := projectFullScale(cfg, prices)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(got) != 1 || got[0].err != nil || got[0].nodes != tt.nodes {
+			t.Errorf("%s: projectFullScale = %+v, want %d nodes", tt.name, got, tt.nodes)
+		}
+	}
+
+	// Every combination is listed, priced ones cheapest first and the ones
+	// that cannot be priced last.
+	cfg := &config.Config{Compute: config.ComputeConfig{
+		MaxCPU:        40,
+		MaxMemory:     "64Gi",
+		InstanceTypes: []string{"unsized", "compute", "small", "unknown"},
+		CapacityTypes: []string{pricing.OnDemand, pricing.Spot},
+	}}
+	got, err := projectFullScale(cfg, prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 8 {
+		t.Fatalf("got %d rows, want 8", len(got))
+	}
+	want := []struct {
+		instanceType, capacityType string
+		hourly                     float64
+	}{
+		{"small", pricing.Spot, 1},
+		{"compute", pricing.OnDemand, 1.5},
+		{"small", pricing.OnDemand, 2},
+	}
+	for i, w := range want {
+		r := got[i]
+		if r.err != nil || r.instanceType != w.instanceType || r.capacityType != w.capacityType || r.hourly != w.hourly {
+			t.Errorf("row %d = %+v, want %s %s at %v", i, r, w.instanceType, w.capacityType, w.hourly)
+		}
+	}
+	for _, r := range got[len(want):] {
+		if r.err == nil {
+			t.Errorf("row %+v has no error, want unpriced rows last", r)
+		}
+	}
+
+	cfg.Compute.MaxMemory = "lots"
+	if _, err := projectFullScale(cfg, prices); err == nil {
+		t.Error("projectFullScale with an invalid compute.maxMemory succeeded")
+	}
+}
@@ -0,0 +1,172 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/rathi/agentikube/internal/archive"
+	"github.com/rathi/agentikube/internal/kube"
+	"github.com/spf13/cobra"
+)
+
+func NewCpCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cp <src> <dst>",
+		Short: "Copy files to or from a sandbox",
+		Long: "Copies files and directories between the local machine and a sandbox using a\n" +
+			"tar stream over exec. Exactly one side must be remote, written as\n" +
+			"<handle>:<path>. Relative remote paths are resolved against sandbox.mountPath.\n" +
+			"A remote destination ending in / is treated as a directory.\n\n" +
+			"  agentikube cp ./repo demo:repo\n" +
+			"  agentikube cp demo:out/report.html .",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			srcHandle, srcPath, srcRemote := parseCopySpec(args[0])
+			dstHandle, dstPath, dstRemote := parseCopySpec(args[1])
+			if srcRemote == dstRemote {
+				return fmt.Errorf("exactly one of <src> and <dst> must be a sandbox path (<handle>:<path>)")
+			}
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			client, err := kube.NewClient()
+			if err != nil {
+				return fmt.Errorf("connecting to cluster: %w", err)
+			}
+
+			ns := cfg.Namespace
+			handle := srcHandle
+			if dstRemote {
+				handle = dstHandle
+			}
+
+			podName, err := resolvePod(ctx, client, ns, handle)
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			keepActive(ctx, client, ns, handle)
+
+			if dstRemote {
+				remote := remotePath(cfg.Sandbox.MountPath, dstPath)
+				dir, name := path.Dir(remote), path.Base(remote)
+				if strings.HasSuffix(dstPath, "/") {
+					dir, name = remote, filepath.Base(filepath.Clean(srcPath))
+				}
+
+				owner := archive.Owner{
+					UID: cfg.Sandbox.SecurityContext.RunAsUser,
+					GID: cfg.Sandbox.SecurityContext.RunAsGroup,
+				}
+				if err := copyToPod(ctx, client, ns, podName, srcPath, dir, name, owner); err != nil {
+					return err
+				}
+				fmt.Printf("[ok] copied %s to %s:%s\n", srcPath, handle, path.Join(dir, name))
+				return nil
+			}
+
+			remote := remotePath(cfg.Sandbox.MountPath, srcPath)
+			dir, name := path.Dir(remote), path.Base(remote)
+
+			target := dstPath
+			if info, err := os.Stat(dstPath); err == nil && info.IsDir() {
+				target = filepath.Join(dstPath, name)
+			}
+
+			if err := copyFromPod(ctx, client, ns, podName, dir, name, target); err != nil {
+				return err
+			}
+			fmt.Printf("[ok] copied %s:%s to %s\n", handle, remote, target)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// copyToPod archives the local path src and unpacks it as dir/name in the pod.
+func copyToPod(ctx context.Context, client *kube.Client, namespace, podName, src, dir, name string, owner archive.Owner) error {
+	if _, err := os.Stat(src); err != nil {
+		return err
+	}
+	total, err := archive.Size(src)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", src, err)
+	}
+
+	prog := newProgress("uploading", total)
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(archive.Write(io.MultiWriter(pw, prog), src, name, owner))
+	}()
+
+	err = streamToPod(ctx, client, namespace, podName, dir, pr)
+	pr.CloseWithError(io.ErrClosedPipe)
+	prog.Done()
+	return err
+}
+
+// copyFromPod archives dir/name in the pod and unpacks it locally as target.
+func copyFromPod(ctx context.Context, client *kube.Client, namespace, podName, dir, name, target string) error {
+	prog := newProgress("downloading", 0)
+	pr, pw := io.Pipe()
+
+	type result struct {
+		skipped []string
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		skipped, err := archive.Extract(pr, target, name)
+		// Drain so the remote tar is never blocked on a partial read.
+		io.Copy(io.Discard, pr)
+		done <- result{skipped, err}
+	}()
+
+	err := streamFromPod(ctx, client, namespace, podName, dir, name, io.MultiWriter(pw, prog))
+	pw.CloseWithError(err)
+	res := <-done
+	prog.Done()
+
+	if err != nil {
+		return err
+	}
+	for _, s := range res.skipped {
+		fmt.Printf("[warn] skipped link %s\n", s)
+	}
+	return res.err
+}
+
+// parseCopySpec splits a cp argument into handle and path. Arguments without
+// a colon or starting with one are local, as are Windows paths with a volume
+// name such as C:\.
+func parseCopySpec(arg string) (handle, p string, remote bool) {
+	if filepath.VolumeName(arg) != "" {
+		return "", arg, false
+	}
+	i := strings.Index(arg, ":")
+	if i <= 0 {
+		return "", arg, false
+	}
+	return arg[:i], arg[i+1:], true
+}
+
+// remotePath resolves p against the sandbox mount path unless it is absolute.
+func remotePath(mountPath, p string) string {
+	if path.IsAbs(p) {
+		return path.Clean(p)
+	}
+	return path.Join(mountPath, p)
+}
@@ -0,0 +1,34 @@
+package commands
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestParseCopySpec(t *testing.T) {
+	type copySpec struct {
+		arg    string
+		handle string
+		path   string
+		remote bool
+	}
+	tests := []copySpec{
+		{"demo:repo", "demo", "repo", true},
+		{"a:path", "a", "path", true},
+		{"demo:", "demo", "", true},
+		{"demo:/tmp/a:b", "demo", "/tmp/a:b", true},
+		{"./repo", "", "./repo", false},
+		{":repo", "", ":repo", false},
+		{"", "", "", false},
+	}
+	if runtime.GOOS == "windows" {
+		tests = append(tests, copySpec{`C:\repo`, "", `C:\repo`, false})
+	}
+	for _, tt := range tests {
+		handle, p, remote := parseCopySpec(tt.arg)
+		if handle != tt.handle || p != tt.path || remote != tt.remote {
+			t.Errorf("parseCopySpec(%q) = %q, %q, %v; want %q, %q, %v",
+				tt.arg, handle, p, remote, tt.handle, tt.path, tt.remote)
+		}
+	}
+}
@@ -2,28 +2,103 @@ package commands
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
 	"time"
 
 	"github.com/rathi/agentikube/internal/kube"
+	"github.com/rathi/agentikube/internal/output"
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// createMode controls how create treats objects left behind by an earlier
+// run for the same handle.
+type createMode int
+
+const (
+	// createNew fails if the Secret or SandboxClaim already exists.
+	createNew createMode = iota
+	// createIfNotExists keeps existing objects as they are and creates the
+	// missing ones.
+	createIfNotExists
+	// createAdopt takes over existing objects, updating their labels,
+	// annotations and any Secret values given on the command line.
+	createAdopt
 )
 
 func NewCreateCmd() *cobra.Command {
 	var provider string
-	var apiKey string
+	var apiKey apiKeyFlags
+	var allowEmptyKey bool
+	var labels []string
+	var annotations []string
+	var description string
+	var ifNotExists bool
+	var adopt bool
+	var timeout time.Duration
+	var noWait bool
 
 	cmd := &cobra.Command{
 		Use:   "create <handle>",
 		Short: "Create a new sandbox for an agent",
-		Long:  "Creates a Secret and SandboxClaim for the given handle, then waits for it to be ready.",
-		Args:  cobra.ExactArgs(1),
+		Long: "Creates a Secret and SandboxClaim for the given handle, then waits for it to be ready.\n" +
+			"Handles use lowercase letters, digits and '-'; long handles are shortened to a\n" +
+			"hashed resource name and the full handle is kept in the agentikube.io/handle\n" +
+			"annotation.\n\n" +
+			"Labels, annotations and the description are set on the Secret, the SandboxClaim\n" +
+			"and the sandbox pod, so they can be used with --selector.\n\n" +
+			"The API key is taken from --api-key, --api-key-file, --api-key-stdin or\n" +
+			"SANDBOX_API_KEY, in that order, and otherwise prompted for when stdin is a\n" +
+			"terminal. --provider falls back to SANDBOX_LLM_PROVIDER.\n\n" +
+			"The Secret is owned by the SandboxClaim and is garbage-collected with it. If\n" +
+			"create fails or is interrupted, the objects it made are deleted again. To\n" +
+			"finish a sandbox left behind by an earlier run, use --if-not-exists to keep\n" +
+			"existing objects or --adopt to also update them. A handle whose workspace PVC\n" +
+			"was kept by destroy --keep-data cannot be created again until that PVC is\n" +
+			"deleted.\n\n" +
+			"While waiting, create reports startup phases (warm pool hit or miss, node\n" +
+			"provisioning, volume attach, image pull, startup probe) and finally the time\n" +
+			"it took the sandbox to become ready. --no-wait skips the wait, and with it the\n" +
+			"rollback of a sandbox that never becomes ready.",
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ctx := context.Background()
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
 			handle := args[0]
 
+			format, err := outputFormat(cmd)
+			if err != nil {
+				return err
+			}
+			out := stdoutFor(format)
+
+			if err := validateHandle(handle); err != nil {
+				return err
+			}
+
+			mode := createNew
+			switch {
+			case adopt:
+				mode = createAdopt
+			case ifNotExists:
+				mode = createIfNotExists
+			}
+
+			meta, err := parseMetadata(labels, annotations, description)
+			if err != nil {
+				return err
+			}
+
 			cfg, err := loadConfig(cmd)
 			if err != nil {
 				return err
@@ -35,77 +110,446 @@ func NewCreateCmd() *cobra.Command {
 			}
 
 			ns := cfg.Namespace
-			name := "sandbox-" + handle
-
-			// Create the secret with provider credentials
-			secret := &unstructured.Unstructured{
-				Object: map[string]interface{}{
-					"apiVersion": "v1",
-					"kind":       "Secret",
-					"metadata": map[string]interface{}{
-						"name":      name,
-						"namespace": ns,
-					},
-					"stringData": map[string]interface{}{
-						"PROVIDER":     provider,
-						"PROVIDER_KEY": apiKey,
-						"USER_NAME":    handle,
-					},
-				},
+
+			existing, err := getSandboxObjects(ctx, client, ns, handle)
+			if err != nil {
+				return err
+			}
+			if err := existing.check(handle, mode); err != nil {
+				return err
 			}
 
-			secretGVR := coreGVR("secrets")
-			_, err = client.Dynamic().Resource(secretGVR).Namespace(ns).Create(ctx, secret, metav1.CreateOptions{})
+			// An existing Secret already holds a key, so only an explicit
+			// flag replaces it and there is nothing to prompt for.
+			var key string
+			if existing.secret != nil {
+				key, _, err = apiKey.read(cmd)
+			} else {
+				key, err = apiKey.resolve(cmd)
+			}
 			if err != nil {
-				return fmt.Errorf("creating secret %q: %w", name, err)
-			}
-			fmt.Printf("[ok] secret %q created\n", name)
-
-			// Create the SandboxClaim
-			claim := &unstructured.Unstructured{
-				Object: map[string]interface{}{
-					"apiVersion": "extensions.agents.x-k8s.io/v1alpha1",
-					"kind":       "SandboxClaim",
-					"metadata": map[string]interface{}{
-						"name":      name,
-						"namespace": ns,
-					},
-					"spec": map[string]interface{}{
-						"templateRef": map[string]interface{}{
-							"name": "sandbox-template",
-						},
-						"secretRef": map[string]interface{}{
-							"name": name,
-						},
-					},
-				},
+				return err
+			}
+			if key == "" && existing.secret == nil && !allowEmptyKey {
+				return fmt.Errorf("no API key given (use --api-key-file, --api-key-stdin or %s, or --allow-empty-key)", apiKeyEnv)
 			}
 
-			_, err = client.Dynamic().Resource(sandboxClaimGVR).Namespace(ns).Create(ctx, claim, metav1.CreateOptions{})
+			start := time.Now()
+			txn, err := createSandbox(ctx, client, out, ns, handle, mode, existing, meta, map[string]string{
+				"PROVIDER":     resolveProvider(cmd, provider),
+				"PROVIDER_KEY": key,
+				"USER_NAME":    handle,
+			})
 			if err != nil {
-				return fmt.Errorf("creating SandboxClaim %q: %w", name, err)
+				return err
 			}
-			fmt.Printf("[ok] SandboxClaim %q created\n", name)
 
-			// Wait for the sandbox to become ready (3 min timeout)
-			fmt.Println("waiting for sandbox to be ready...")
-			waitCtx, cancel := context.WithTimeout(ctx, 3*time.Minute)
-			defer cancel()
+			if noWait {
+				fmt.Fprintf(out, "[ok] sandbox %q created; not waiting for it to become ready (see: agentikube list)\n", handle)
+				if format == output.Table {
+					return nil
+				}
+				return printCreatedSandbox(ctx, client, format, ns, handle)
+			}
+
+			if err := waitSandboxReady(ctx, client, out, ns, handle, timeout); err != nil {
+				txn.rollback(out)
+				return err
+			}
+			if err := syncPodMetadata(ctx, client, ns, handle); err != nil {
+				fmt.Fprintf(out, "[warn] %v\n", err)
+			}
 
-			if err := client.WaitForReady(waitCtx, ns, sandboxClaimGVR, name); err != nil {
-				return fmt.Errorf("waiting for sandbox: %w", err)
+			elapsed := time.Since(start).Round(time.Second)
+			err = annotateClaim(ctx, client, ns, sandboxName(handle), map[string]interface{}{
+				readyAfterAnnotation: elapsed.String(),
+			})
+			if err != nil {
+				fmt.Fprintf(out, "[warn] %v\n", err)
 			}
+			fmt.Fprintf(out, "[ok] ready after %s\n", elapsed)
 
-			fmt.Printf("\nsandbox %q is ready\n", handle)
-			fmt.Printf("  name:      %s\n", name)
-			fmt.Printf("  namespace: %s\n", ns)
-			fmt.Printf("  ssh:       agentikube ssh %s\n", handle)
-			return nil
+			if format == output.Table {
+				printSandboxReady(ns, handle)
+				return nil
+			}
+			return printCreatedSandbox(ctx, client, format, ns, handle)
 		},
 	}
 
 	cmd.Flags().StringVar(&provider, "provider", "", "LLM provider name (env: SANDBOX_LLM_PROVIDER)")
-	cmd.Flags().StringVar(&apiKey, "api-key", "", "LLM provider API key (env: SANDBOX_API_KEY)")
+	apiKey.register(cmd, "LLM provider API key (env: SANDBOX_API_KEY)")
+	cmd.Flags().BoolVar(&allowEmptyKey, "allow-empty-key", false, "create the sandbox without an API key")
+	cmd.Flags().StringArrayVar(&labels, "label", nil, "label to set as key=value (repeatable)")
+	cmd.Flags().StringArrayVar(&annotations, "annotation", nil, "annotation to set as key=value (repeatable)")
+	cmd.Flags().StringVar(&description, "description", "", "free-form description of the sandbox")
+	cmd.Flags().BoolVar(&ifNotExists, "if-not-exists", false, "keep existing objects for this handle and create the missing ones")
+	cmd.Flags().BoolVar(&adopt, "adopt", false, "take over and update existing objects for this handle")
+	cmd.MarkFlagsMutuallyExclusive("if-not-exists", "adopt")
+	cmd.Flags().DurationVar(&timeout, "timeout", defaultReadyTimeout, "how long to wait for the sandbox to become ready")
+	cmd.Flags().BoolVar(&noWait, "no-wait", false, "return once the sandbox is created without waiting for it to be ready")
 
 	return cmd
 }
+
+// sandboxObjects holds the existing Secret and SandboxClaim of a handle. A
+// missing object is nil. keptPVCs names the workspace PVCs left behind for
+// the handle by destroy --keep-data.
+type sandboxObjects struct {
+	secret   *corev1.Secret
+	claim    *unstructured.Unstructured
+	keptPVCs []string
+}
+
+func getSandboxObjects(ctx context.Context, client *kube.Client, namespace, handle string) (sandboxObjects, error) {
+	name := sandboxName(handle)
+	var objs sandboxObjects
+
+	secret, err := client.Clientset().CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		objs.secret = secret
+	case !errors.IsNotFound(err):
+		return objs, fmt.Errorf("getting Secret %q: %w", name, err)
+	}
+
+	claim, err := client.Dynamic().Resource(sandboxClaimGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		if got := handleFromClaim(claim); got != handle {
+			return objs, fmt.Errorf("SandboxClaim %q belongs to handle %q, not %q", name, got, handle)
+		}
+		objs.claim = claim
+	case !errors.IsNotFound(err):
+		return objs, fmt.Errorf("getting SandboxClaim %q: %w", name, err)
+	}
+
+	pvcs, err := client.Clientset().CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return objs, fmt.Errorf("listing PVCs: %w", err)
+	}
+	for _, pvc := range pvcs.Items {
+		if pvc.Annotations[handleAnnotation] == handle && len(pvc.OwnerReferences) == 0 {
+			objs.keptPVCs = append(objs.keptPVCs, pvc.Name)
+		}
+	}
+
+	return objs, nil
+}
+
+// check fails in createNew mode if any object already exists, and in every
+// mode if a new claim would be created while a kept workspace PVC exists.
+// The new sandbox would start on an empty workspace instead of the kept one.
+func (o sandboxObjects) check(handle string, mode createMode) error {
+	if o.claim == nil && len(o.keptPVCs) > 0 {
+		return fmt.Errorf("sandbox %q has a workspace kept by destroy --keep-data (PVC %s); "+
+			"it cannot be reattached, so copy the data out and delete the PVC before creating %q again",
+			handle, strings.Join(o.keptPVCs, ", "), handle)
+	}
+	if mode != createNew || (o.secret == nil && o.claim == nil) {
+		return nil
+	}
+	what := "SandboxClaim"
+	if o.claim == nil {
+		what = "Secret"
+	}
+	return fmt.Errorf("sandbox %q already exists (%s found); rerun with --if-not-exists or --adopt to finish it", handle, what)
+}
+
+// createTxn records the objects createSandbox created so that a failed
+// create can remove them again. Objects that already existed are never
+// rolled back.
+type createTxn struct {
+	client    *kube.Client
+	namespace string
+	name      string
+	secret    bool
+	claim     bool
+
+	// claimUID is set when a Secret that already existed was made owned by
+	// a claim created in this transaction. Rollback removes that owner
+	// reference first so the garbage collector keeps the Secret.
+	claimUID types.UID
+}
+
+// rollback deletes the objects created by the transaction. It uses its own
+// context so it still runs after the create was interrupted.
+func (t *createTxn) rollback(out io.Writer) {
+	if t == nil || (!t.secret && !t.claim) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	fmt.Fprintln(out, "rolling back...")
+	if t.claimUID != "" {
+		if err := removeOwnerReference(ctx, t.client, t.namespace, t.name, t.claimUID); err != nil {
+			// Deleting the claim now would take the Secret with it.
+			fmt.Fprintf(out, "[warn] could not release Secret %q from SandboxClaim %q, keeping both: %v\n", t.name, t.name, err)
+			return
+		}
+	}
+	if t.claim {
+		err := t.client.Dynamic().Resource(sandboxClaimGVR).Namespace(t.namespace).Delete(ctx, t.name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			fmt.Fprintf(out, "[warn] could not delete SandboxClaim %q: %v\n", t.name, err)
+		} else {
+			fmt.Fprintf(out, "[ok] SandboxClaim %q deleted\n", t.name)
+		}
+	}
+	if t.secret {
+		err := t.client.Clientset().CoreV1().Secrets(t.namespace).Delete(ctx, t.name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			fmt.Fprintf(out, "[warn] could not delete Secret %q: %v\n", t.name, err)
+		} else {
+			fmt.Fprintf(out, "[ok] Secret %q deleted\n", t.name)
+		}
+	}
+}
+
+// createSandbox creates the Secret holding secretData and the SandboxClaim
+// for handle, both carrying meta, and makes the claim own the Secret. The
+// existing objects, as returned by getSandboxObjects and checked against
+// mode by the caller, are handled according to mode. On failure everything
+// it created is removed; on success the returned transaction can still be
+// rolled back by the caller.
+func createSandbox(ctx context.Context, client *kube.Client, out io.Writer, namespace, handle string, mode createMode, existing sandboxObjects, meta sandboxMetadata, secretData map[string]string) (*createTxn, error) {
+	name := sandboxName(handle)
+	meta = meta.withHandle(handle)
+	txn := &createTxn{client: client, namespace: namespace, name: name}
+
+	fail := func(err error) (*createTxn, error) {
+		txn.rollback(out)
+		return nil, err
+	}
+
+	secretGVR := coreGVR("secrets")
+
+	switch {
+	case existing.secret == nil:
+		// Create the secret with provider credentials
+		secret := &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Secret",
+				"metadata": map[string]interface{}{
+					"name":        name,
+					"namespace":   namespace,
+					"labels":      stringMap(meta.Labels),
+					"annotations": stringMap(meta.Annotations),
+				},
+				"stringData": stringMap(secretData),
+			},
+		}
+
+		_, err := client.Dynamic().Resource(secretGVR).Namespace(namespace).Create(ctx, secret, metav1.CreateOptions{})
+		if err != nil {
+			return fail(fmt.Errorf("creating secret %q: %w", name, err))
+		}
+		txn.secret = true
+		fmt.Fprintf(out, "[ok] secret %q created\n", name)
+	case mode == createAdopt:
+		// Only overwrite values that were actually given.
+		given := map[string]string{}
+		for k, v := range secretData {
+			if v != "" {
+				given[k] = v
+			}
+		}
+		patch := map[string]interface{}{
+			"metadata":   metadataPatch(meta),
+			"stringData": stringMap(given),
+		}
+		if err := mergePatch(ctx, client, secretGVR, namespace, name, patch); err != nil {
+			return fail(fmt.Errorf("updating secret %q: %w", name, err))
+		}
+		fmt.Fprintf(out, "[ok] secret %q adopted\n", name)
+	default:
+		fmt.Fprintf(out, "secret %q already exists, keeping it\n", name)
+	}
+
+	claim := existing.claim
+	var err error
+	switch {
+	case claim == nil:
+		// Create the SandboxClaim
+		obj := &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "extensions.agents.x-k8s.io/v1alpha1",
+				"kind":       "SandboxClaim",
+				"metadata": map[string]interface{}{
+					"name":        name,
+					"namespace":   namespace,
+					"labels":      stringMap(meta.Labels),
+					"annotations": stringMap(meta.Annotations),
+				},
+				"spec": map[string]interface{}{
+					"templateRef": map[string]interface{}{
+						"name": templateName,
+					},
+					"secretRef": map[string]interface{}{
+						"name": name,
+					},
+				},
+			},
+		}
+
+		claim, err = client.Dynamic().Resource(sandboxClaimGVR).Namespace(namespace).Create(ctx, obj, metav1.CreateOptions{})
+		if err != nil {
+			return fail(fmt.Errorf("creating SandboxClaim %q: %w", name, err))
+		}
+		txn.claim = true
+		fmt.Fprintf(out, "[ok] SandboxClaim %q created\n", name)
+	case mode == createAdopt:
+		patch := map[string]interface{}{"metadata": metadataPatch(meta)}
+		if err := mergePatch(ctx, client, sandboxClaimGVR, namespace, name, patch); err != nil {
+			return fail(fmt.Errorf("updating SandboxClaim %q: %w", name, err))
+		}
+		fmt.Fprintf(out, "[ok] SandboxClaim %q adopted\n", name)
+	default:
+		fmt.Fprintf(out, "SandboxClaim %q already exists, keeping it\n", name)
+	}
+
+	// The claim only exists now, so the owner reference is added last.
+	var owners []metav1.OwnerReference
+	if existing.secret != nil {
+		owners = existing.secret.OwnerReferences
+	}
+	if !ownedBy(owners, claim.GetUID()) {
+		if err := addOwnerReference(ctx, client, namespace, name, len(owners) > 0, claim); err != nil {
+			return fail(fmt.Errorf("setting owner of secret %q: %w", name, err))
+		}
+		if existing.secret != nil && txn.claim {
+			txn.claimUID = claim.GetUID()
+		}
+	}
+
+	return txn, nil
+}
+
+// addOwnerReference makes the Secret name owned by claim, keeping any owners
+// it already has.
+func addOwnerReference(ctx context.Context, client *kube.Client, namespace, name string, hasOwners bool, claim *unstructured.Unstructured) error {
+	ref := map[string]interface{}{
+		"apiVersion": claim.GetAPIVersion(),
+		"kind":       claim.GetKind(),
+		"name":       claim.GetName(),
+		"uid":        string(claim.GetUID()),
+	}
+	op := map[string]interface{}{"op": "add", "path": "/metadata/ownerReferences/-", "value": ref}
+	if !hasOwners {
+		op = map[string]interface{}{"op": "add", "path": "/metadata/ownerReferences", "value": []interface{}{ref}}
+	}
+	return jsonPatchSecret(ctx, client, namespace, name, []interface{}{op})
+}
+
+// removeOwnerReference removes the owner reference to uid from the Secret
+// name, leaving its other owners in place.
+func removeOwnerReference(ctx context.Context, client *kube.Client, namespace, name string, uid types.UID) error {
+	secret, err := client.Clientset().CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	for i, ref := range secret.OwnerReferences {
+		if ref.UID != uid {
+			continue
+		}
+		path := fmt.Sprintf("/metadata/ownerReferences/%d", i)
+		return jsonPatchSecret(ctx, client, namespace, name, []interface{}{
+			// The test guards against the list changing since the Get.
+			map[string]interface{}{"op": "test", "path": path + "/uid", "value": string(uid)},
+			map[string]interface{}{"op": "remove", "path": path},
+		})
+	}
+	return nil
+}
+
+func jsonPatchSecret(ctx context.Context, client *kube.Client, namespace, name string, ops []interface{}) error {
+	data, err := json.Marshal(ops)
+	if err != nil {
+		return err
+	}
+	_, err = client.Clientset().CoreV1().Secrets(namespace).Patch(ctx, name, types.JSONPatchType, data, metav1.PatchOptions{})
+	return err
+}
+
+// metadataPatch returns a merge patch for meta's labels and annotations.
+func metadataPatch(meta sandboxMetadata) map[string]interface{} {
+	return map[string]interface{}{
+		"labels":      stringMap(meta.Labels),
+		"annotations": stringMap(meta.Annotations),
+	}
+}
+
+func mergePatch(ctx context.Context, client *kube.Client, gvr schema.GroupVersionResource, namespace, name string, patch map[string]interface{}) error {
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	_, err = client.Dynamic().Resource(gvr).Namespace(namespace).Patch(ctx, name, types.MergePatchType, data, metav1.PatchOptions{})
+	return err
+}
+
+func ownedBy(refs []metav1.OwnerReference, uid types.UID) bool {
+	for _, ref := range refs {
+		if ref.UID == uid {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultReadyTimeout is how long commands wait for a sandbox to become
+// ready unless told otherwise.
+const defaultReadyTimeout = 3 * time.Minute
+
+// waitSandboxReady waits up to timeout for the SandboxClaim for handle to
+// become ready, reporting startup phases to out meanwhile.
+func waitSandboxReady(ctx context.Context, client *kube.Client, out io.Writer, namespace, handle string, timeout time.Duration) error {
+	fmt.Fprintln(out, "waiting for sandbox to be ready...")
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	trackCtx, stopTracking := context.WithCancel(waitCtx)
+	tracked := make(chan struct{})
+	go func() {
+		newPhaseTracker(client, out, namespace, handle).run(trackCtx)
+		close(tracked)
+	}()
+
+	err := client.WaitForReady(waitCtx, namespace, sandboxClaimGVR, sandboxName(handle))
+	stopTracking()
+	<-tracked
+	if err != nil {
+		return fmt.Errorf("waiting for sandbox: %w", err)
+	}
+	return nil
+}
+
+func printSandboxReady(namespace, handle string) {
+	fmt.Printf("\nsandbox %q is ready\n", handle)
+	fmt.Printf("  name:      %s\n", sandboxName(handle))
+	fmt.Printf("  namespace: %s\n", namespace)
+	fmt.Printf("  ssh:       agentikube ssh %s\n", handle)
+}
+
+// printCreatedSandbox writes the newly created sandbox in a non-default
+// output format, including its pod and node.
+func printCreatedSandbox(ctx context.Context, client *kube.Client, format, namespace, handle string) error {
+	name := sandboxName(handle)
+	claim, err := client.Dynamic().Resource(sandboxClaimGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("getting SandboxClaim %q: %w", name, err)
+	}
+
+	p, err := lookupPlacement(ctx, client, namespace)
+	if err != nil {
+		return err
+	}
+
+	s := sandboxResult(claim, p)
+	if output.IsStructured(format) {
+		return output.Write(os.Stdout, format, s)
+	}
+	return printSandboxes(os.Stdout, format, []output.Sandbox{s}, nil)
+}
@@ -0,0 +1,144 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/rathi/agentikube/internal/kube"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// setClaimUID makes the fake API server assign uid to created claims, as the
+// real one would.
+func setClaimUID(dyn *dynamicfake.FakeDynamicClient, uid types.UID) {
+	dyn.PrependReactor("create", "sandboxclaims", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		action.(k8stesting.CreateAction).GetObject().(*unstructured.Unstructured).SetUID(uid)
+		return false, nil, nil
+	})
+}
+
+func testSecret(handle string, owners ...metav1.OwnerReference) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: sandboxName(handle), Namespace: testNamespace, OwnerReferences: owners},
+		Data:       map[string][]byte{"PROVIDER": []byte("openai"), "PROVIDER_KEY": []byte("old-key")},
+	}
+}
+
+func TestCreateSandboxRollsBackSecretWhenClaimFails(t *testing.T) {
+	dyn := newFakeDynamic()
+	dyn.PrependReactor("create", "sandboxclaims", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(sandboxClaimGVR.GroupResource(), sandboxName("demo"), errors.New("denied"))
+	})
+	clientset := fake.NewSimpleClientset()
+	client := kube.NewClientFrom(dyn, clientset)
+
+	var out bytes.Buffer
+	txn, err := createSandbox(context.Background(), client, &out, testNamespace, "demo", createNew, sandboxObjects{}, sandboxMetadata{}, map[string]string{"PROVIDER_KEY": "key"})
+	if err == nil || txn != nil {
+		t.Fatalf("createSandbox = %v, %v, want an error and no transaction", txn, err)
+	}
+	if !apierrors.IsForbidden(err) {
+		t.Errorf("err = %v, want the claim create error", err)
+	}
+
+	// The Secret was created before the claim failed and must be deleted.
+	deleted := false
+	for _, a := range clientset.Actions() {
+		if d, ok := a.(k8stesting.DeleteAction); ok && a.GetResource().Resource == "secrets" && d.GetName() == sandboxName("demo") {
+			deleted = true
+		}
+	}
+	if !deleted {
+		t.Errorf("the Secret created before the claim failed was not deleted; output:\n%s", out.String())
+	}
+}
+
+func TestCreateSandboxRollbackKeepsExistingSecret(t *testing.T) {
+	other := metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "other", UID: "other-uid"}
+	secret := testSecret("demo", other)
+	dyn := newFakeDynamic()
+	setClaimUID(dyn, "claim-uid")
+	clientset := fake.NewSimpleClientset(secret)
+	client := kube.NewClientFrom(dyn, clientset)
+
+	var out bytes.Buffer
+	existing := sandboxObjects{secret: secret}
+	txn, err := createSandbox(context.Background(), client, &out, testNamespace, "demo", createIfNotExists, existing, sandboxMetadata{}, map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := clientset.CoreV1().Secrets(testNamespace).Get(context.Background(), secret.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ownedBy(got.OwnerReferences, "claim-uid") || !ownedBy(got.OwnerReferences, other.UID) {
+		t.Fatalf("owners = %v, want the claim added to the existing owner", got.OwnerReferences)
+	}
+
+	txn.rollback(&out)
+
+	got, err = clientset.CoreV1().Secrets(testNamespace).Get(context.Background(), secret.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("the existing Secret was deleted by the rollback: %v", err)
+	}
+	if len(got.OwnerReferences) != 1 || got.OwnerReferences[0].UID != other.UID {
+		t.Errorf("owners after rollback = %v, want only %s", got.OwnerReferences, other.UID)
+	}
+	_, err = dyn.Resource(sandboxClaimGVR).Namespace(testNamespace).Get(context.Background(), sandboxName("demo"), metav1.GetOptions{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("the created SandboxClaim was not deleted: %v", err)
+	}
+}
+
+func TestCreateSandboxAdoptKeepsKey(t *testing.T) {
+	claim := testClaim("demo", "sandbox-demo", false)
+	claim.SetUID("claim-uid")
+	secret := testSecret("demo", metav1.OwnerReference{Kind: "SandboxClaim", Name: claim.GetName(), UID: "claim-uid"})
+	secretObj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secretU := &unstructured.Unstructured{Object: secretObj}
+	secretU.SetAPIVersion("v1")
+	secretU.SetKind("Secret")
+	dyn := newFakeDynamic(claim, secretU)
+	client := kube.NewClientFrom(dyn, fake.NewSimpleClientset(secret))
+
+	// No key flag was given, so create passes an empty key.
+	var out bytes.Buffer
+	existing := sandboxObjects{secret: secret, claim: claim}
+	_, err = createSandbox(context.Background(), client, &out, testNamespace, "demo", createAdopt, existing, sandboxMetadata{}, map[string]string{
+		"PROVIDER":     "anthropic",
+		"PROVIDER_KEY": "",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := dyn.Resource(coreGVR("secrets")).Namespace(testNamespace).Get(context.Background(), secret.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stringData, _, _ := unstructured.NestedStringMap(got.Object, "stringData")
+	if stringData["PROVIDER"] != "anthropic" {
+		t.Errorf("stringData = %v, want PROVIDER updated", stringData)
+	}
+	if _, ok := stringData["PROVIDER_KEY"]; ok {
+		t.Errorf("stringData = %v, want the existing PROVIDER_KEY left alone", stringData)
+	}
+	if key, _, _ := unstructured.NestedString(got.Object, "data", "PROVIDER_KEY"); key != base64.StdEncoding.EncodeToString([]byte("old-key")) {
+		t.Errorf("data.PROVIDER_KEY = %q, want the old key", key)
+	}
+}
@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// Environment variables read by create when the matching flag is not set.
+const (
+	providerEnv = "SANDBOX_LLM_PROVIDER"
+	apiKeyEnv   = "SANDBOX_API_KEY"
+)
+
+// apiKeyFlags are the ways of passing a provider API key on the command
+// line. --api-key is kept for scripts but ends up in shell history and ps.
+type apiKeyFlags struct {
+	key   string
+	file  string
+	stdin bool
+}
+
+func (f *apiKeyFlags) register(cmd *cobra.Command, usage string) {
+	cmd.Flags().StringVar(&f.key, "api-key", "", usage+" (visible in shell history; prefer --api-key-file)")
+	cmd.Flags().StringVar(&f.file, "api-key-file", "", "read the API key from this file")
+	cmd.Flags().BoolVar(&f.stdin, "api-key-stdin", false, "read the API key from stdin")
+	cmd.MarkFlagsMutuallyExclusive("api-key", "api-key-file", "api-key-stdin")
+}
+
+// read returns the key from whichever flag was given. It reports false when
+// none of them was set.
+func (f *apiKeyFlags) read(cmd *cobra.Command) (string, bool, error) {
+	switch {
+	case cmd.Flags().Changed("api-key"):
+		return f.key, true, nil
+	case f.file != "":
+		data, err := os.ReadFile(f.file)
+		if err != nil {
+			return "", false, fmt.Errorf("reading API key: %w", err)
+		}
+		return trimKey(data), true, nil
+	case f.stdin:
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", false, fmt.Errorf("reading API key from stdin: %w", err)
+		}
+		return trimKey(data), true, nil
+	default:
+		return "", false, nil
+	}
+}
+
+// resolve returns the key from the flags, then SANDBOX_API_KEY, then an
+// interactive prompt when stdin is a terminal. It returns "" if none of
+// them supplied a key.
+func (f *apiKeyFlags) resolve(cmd *cobra.Command) (string, error) {
+	key, ok, err := f.read(cmd)
+	if err != nil || ok {
+		return key, err
+	}
+	if key, ok := os.LookupEnv(apiKeyEnv); ok {
+		return key, nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", nil
+	}
+
+	fmt.Fprint(os.Stderr, "API key: ")
+	data, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("reading API key: %w", err)
+	}
+	return trimKey(data), nil
+}
+
+// trimKey strips surrounding whitespace, including the trailing newline
+// most editors and `echo` add.
+func trimKey(data []byte) string {
+	return strings.TrimSpace(string(data))
+}
+
+// resolveProvider returns --provider if set, otherwise SANDBOX_LLM_PROVIDER.
+func resolveProvider(cmd *cobra.Command, provider string) string {
+	if cmd.Flags().Changed("provider") {
+		return provider
+	}
+	return os.Getenv(providerEnv)
+}
@@ -3,38 +3,60 @@ package commands
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/rathi/agentikube/internal/kube"
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
 )
 
+// protectedLabel marks a sandbox that destroy refuses to delete without
+// --force.
+const protectedLabel = "agentikube.io/protected"
+
 func NewDestroyCmd() *cobra.Command {
 	var yes bool
+	var selector string
+	var all bool
+	var keepData bool
+	var force bool
+	var parallel int
 
 	cmd := &cobra.Command{
-		Use:   "destroy <handle>",
+		Use:   "destroy [handle...]",
 		Short: "Destroy a sandbox and its resources",
-		Long:  "Deletes the SandboxClaim, Secret, and PVC for the given handle.",
-		Args:  cobra.ExactArgs(1),
+		Long: "Deletes the SandboxClaim, Secret, and PVC for the given handles, for every\n" +
+			"sandbox matching --selector, or for every sandbox in the namespace with --all.\n" +
+			"--all asks you to type the namespace name to confirm.\n\n" +
+			"--keep-data keeps the workspace PVC, detached from the sandbox so it is not\n" +
+			"garbage-collected and annotated with " + handleAnnotation + ". It is not\n" +
+			"reattached: create and clone refuse the handle while the PVC exists, so copy\n" +
+			"the data out of it and delete it when done. Sandboxes labeled\n" +
+			protectedLabel + "=true are skipped unless --force is given.\n\n" +
+			"Sandboxes are deleted concurrently; failures are reported per handle.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := context.Background()
-			handle := args[0]
+			handles := args
 
-			if !yes {
-				fmt.Printf("are you sure you want to destroy sandbox %q? [y/N] ", handle)
-				scanner := bufio.NewScanner(os.Stdin)
-				scanner.Scan()
-				answer := strings.TrimSpace(strings.ToLower(scanner.Text()))
-				if answer != "y" && answer != "yes" {
-					fmt.Println("aborted")
-					return nil
+			sources := 0
+			for _, set := range []bool{len(handles) > 0, selector != "", all} {
+				if set {
+					sources++
 				}
 			}
+			if sources != 1 {
+				return fmt.Errorf("specify handles, --selector or --all")
+			}
 
 			cfg, err := loadConfig(cmd)
 			if err != nil {
@@ -47,41 +69,242 @@ func NewDestroyCmd() *cobra.Command {
 			}
 
 			ns := cfg.Namespace
-			name := "sandbox-" + handle
 
-			secretGVR := coreGVR("secrets")
-			pvcGVR := coreGVR("persistentvolumeclaims")
-
-			// Delete SandboxClaim
-			err = client.Dynamic().Resource(sandboxClaimGVR).Namespace(ns).Delete(ctx, name, metav1.DeleteOptions{})
-			if err != nil {
-				return fmt.Errorf("deleting SandboxClaim %q: %w", name, err)
+			if selector != "" || all {
+				list, err := client.Dynamic().Resource(sandboxClaimGVR).Namespace(ns).List(ctx, metav1.ListOptions{
+					LabelSelector: selector,
+				})
+				if err != nil {
+					return fmt.Errorf("listing SandboxClaims: %w", err)
+				}
+				for _, item := range list.Items {
+					handles = append(handles, handleFromClaim(&item))
+				}
+				if len(handles) == 0 {
+					if all {
+						fmt.Printf("no sandboxes in namespace %q\n", ns)
+						return nil
+					}
+					return fmt.Errorf("no sandboxes match selector %q", selector)
+				}
 			}
-			fmt.Printf("[ok] SandboxClaim %q deleted\n", name)
 
-			// Delete Secret
-			err = client.Dynamic().Resource(secretGVR).Namespace(ns).Delete(ctx, name, metav1.DeleteOptions{})
-			if err != nil {
-				return fmt.Errorf("deleting Secret %q: %w", name, err)
+			if !yes {
+				var ok bool
+				switch {
+				case all:
+					ok = confirmTyped(fmt.Sprintf("this destroys all %d sandboxes in namespace %q. Type the namespace name to confirm: ", len(handles), ns), ns)
+				case len(handles) == 1:
+					ok = confirm(fmt.Sprintf("are you sure you want to destroy sandbox %q? [y/N] ", handles[0]))
+				default:
+					ok = confirm(fmt.Sprintf("are you sure you want to destroy %d sandboxes (%s)? [y/N] ", len(handles), strings.Join(handles, ", ")))
+				}
+				if !ok {
+					fmt.Println("aborted")
+					return nil
+				}
 			}
-			fmt.Printf("[ok] Secret %q deleted\n", name)
 
-			// Delete PVC (best-effort)
-			err = client.Dynamic().Resource(pvcGVR).Namespace(ns).Delete(ctx, name, metav1.DeleteOptions{})
-			if err != nil {
-				if !errors.IsNotFound(err) {
-					fmt.Printf("[warn] could not delete PVC %q: %v\n", name, err)
+			opts := destroyOptions{keepData: keepData, force: force}
+			if len(handles) == 1 {
+				if err := destroySandbox(ctx, client, os.Stdout, ns, handles[0], opts); err != nil {
+					return err
 				}
-			} else {
-				fmt.Printf("[ok] PVC %q deleted\n", name)
+				fmt.Printf("\nsandbox %q destroyed\n", handles[0])
+				return nil
 			}
-
-			fmt.Printf("\nsandbox %q destroyed\n", handle)
-			return nil
+			return destroyMany(ctx, client, os.Stdout, ns, handles, opts, parallel)
 		},
 	}
 
 	cmd.Flags().BoolVar(&yes, "yes", false, "skip confirmation prompt")
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "destroy every sandbox matching this label selector")
+	cmd.Flags().BoolVar(&all, "all", false, "destroy every sandbox in the namespace")
+	cmd.Flags().BoolVar(&keepData, "keep-data", false, "keep the workspace PVC, detached from the sandbox")
+	cmd.Flags().BoolVar(&force, "force", false, "also destroy sandboxes labeled "+protectedLabel+"=true")
+	cmd.Flags().IntVar(&parallel, "parallel", 5, "maximum number of sandboxes to destroy at once")
 
 	return cmd
 }
+
+func confirm(prompt string) bool {
+	fmt.Print(prompt)
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Scan()
+	answer := strings.TrimSpace(strings.ToLower(scanner.Text()))
+	return answer == "y" || answer == "yes"
+}
+
+// confirmTyped asks the user to type want exactly.
+func confirmTyped(prompt, want string) bool {
+	fmt.Print(prompt)
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Scan()
+	return strings.TrimSpace(scanner.Text()) == want
+}
+
+type destroyOptions struct {
+	keepData bool
+	force    bool
+}
+
+// destroyMany destroys handles concurrently, at most parallel at a time,
+// prefixing output lines with the handle. It reports every failure at the
+// end instead of stopping at the first one.
+func destroyMany(ctx context.Context, client *kube.Client, w io.Writer, namespace string, handles []string, opts destroyOptions, parallel int) error {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	width := 0
+	for _, h := range handles {
+		width = max(width, len(h))
+	}
+
+	var outMu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, parallel)
+	failures := map[string]error{}
+	var failMu sync.Mutex
+
+	for _, handle := range handles {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			out := newPrefixWriter(&outMu, w, fmt.Sprintf("%-*s | ", width, handle))
+			err := destroySandbox(ctx, client, out, namespace, handle, opts)
+			out.Flush()
+
+			if err != nil {
+				failMu.Lock()
+				failures[handle] = err
+				failMu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	fmt.Fprintf(w, "\n%d of %d sandboxes destroyed\n", len(handles)-len(failures), len(handles))
+	if len(failures) == 0 {
+		return nil
+	}
+
+	failed := make([]string, 0, len(failures))
+	for handle := range failures {
+		failed = append(failed, handle)
+	}
+	sort.Strings(failed)
+	for _, handle := range failed {
+		fmt.Fprintf(w, "  [fail] %s: %v\n", handle, failures[handle])
+	}
+	return fmt.Errorf("%d of %d sandboxes could not be destroyed", len(failures), len(handles))
+}
+
+// destroySandbox deletes the SandboxClaim, Secret and workspace PVCs for
+// handle, writing progress to out.
+func destroySandbox(ctx context.Context, client *kube.Client, out io.Writer, ns, handle string, opts destroyOptions) error {
+	name := sandboxName(handle)
+
+	claim, err := getClaim(ctx, client, ns, handle)
+	if err != nil {
+		return err
+	}
+	if claim.GetLabels()[protectedLabel] == "true" && !opts.force {
+		return fmt.Errorf("sandbox %q is protected (label %s=true); use --force to destroy it", handle, protectedLabel)
+	}
+
+	// PVCs are looked up before the claim goes away, as the Sandbox that
+	// owns them is deleted with it.
+	pvcs, err := sandboxPVCs(ctx, client, ns, claim)
+	if err != nil {
+		fmt.Fprintf(out, "[warn] could not list PVCs: %v\n", err)
+	}
+	if opts.keepData {
+		for _, pvc := range pvcs {
+			if err := detachPVC(ctx, client, ns, pvc.Name, handle); err != nil {
+				return fmt.Errorf("keeping PVC %q: %w", pvc.Name, err)
+			}
+			fmt.Fprintf(out, "[ok] PVC %q kept\n", pvc.Name)
+		}
+	}
+
+	secretGVR := coreGVR("secrets")
+
+	// Delete SandboxClaim
+	err = client.Dynamic().Resource(sandboxClaimGVR).Namespace(ns).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil {
+		return fmt.Errorf("deleting SandboxClaim %q: %w", name, err)
+	}
+	fmt.Fprintf(out, "[ok] SandboxClaim %q deleted\n", name)
+
+	// Delete Secret. It is owned by the claim, so the garbage collector may
+	// have removed it already.
+	err = client.Dynamic().Resource(secretGVR).Namespace(ns).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("deleting Secret %q: %w", name, err)
+	}
+	fmt.Fprintf(out, "[ok] Secret %q deleted\n", name)
+
+	if opts.keepData {
+		return nil
+	}
+
+	// Delete PVCs (best-effort)
+	for _, pvc := range pvcs {
+		err := client.Clientset().CoreV1().PersistentVolumeClaims(ns).Delete(ctx, pvc.Name, metav1.DeleteOptions{})
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				fmt.Fprintf(out, "[warn] could not delete PVC %q: %v\n", pvc.Name, err)
+			}
+			continue
+		}
+		fmt.Fprintf(out, "[ok] PVC %q deleted\n", pvc.Name)
+	}
+	return nil
+}
+
+// sandboxPVCs returns the workspace PVCs of a sandbox: the PVC named after
+// the claim and any PVC owned by its Sandbox.
+func sandboxPVCs(ctx context.Context, client *kube.Client, ns string, claim *unstructured.Unstructured) ([]corev1.PersistentVolumeClaim, error) {
+	list, err := client.Clientset().CoreV1().PersistentVolumeClaims(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	sandboxName := extractSandboxName(claim.Object)
+	var pvcs []corev1.PersistentVolumeClaim
+	for _, pvc := range list.Items {
+		if pvc.Name == claim.GetName() || ownedBySandbox(pvc.OwnerReferences, sandboxName) {
+			pvcs = append(pvcs, pvc)
+		}
+	}
+	return pvcs, nil
+}
+
+func ownedBySandbox(refs []metav1.OwnerReference, name string) bool {
+	for _, ref := range refs {
+		if ref.Kind == "Sandbox" && ref.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// detachPVC removes the owner references of a PVC so deleting the sandbox
+// does not garbage-collect it, and records the handle it belonged to.
+func detachPVC(ctx context.Context, client *kube.Client, ns, name, handle string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"ownerReferences": nil,
+			"annotations":     map[string]interface{}{handleAnnotation: handle},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = client.Clientset().CoreV1().PersistentVolumeClaims(ns).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
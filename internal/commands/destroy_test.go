@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rathi/agentikube/internal/kube"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDestroyManyReportsEveryFailure(t *testing.T) {
+	locked := testClaim("locked", "pod-locked", false)
+	locked.SetLabels(map[string]string{protectedLabel: "true"})
+	dyn := newFakeDynamic(testClaim("ok", "pod-ok", false), locked)
+	client := kube.NewClientFrom(dyn, fake.NewSimpleClientset())
+
+	var out bytes.Buffer
+	err := destroyMany(context.Background(), client, &out, testNamespace, []string{"locked", "ok", "gone"}, destroyOptions{}, 2)
+	if err == nil || err.Error() != "2 of 3 sandboxes could not be destroyed" {
+		t.Fatalf("err = %v, want 2 of 3 failures", err)
+	}
+
+	output := out.String()
+	if !strings.Contains(output, "1 of 3 sandboxes destroyed") {
+		t.Errorf("output does not report the destroyed count:\n%s", output)
+	}
+	gone := strings.Index(output, "  [fail] gone: ")
+	lockedFail := strings.Index(output, "  [fail] locked: ")
+	if gone < 0 || lockedFail < 0 || gone > lockedFail {
+		t.Errorf("output does not list both failures in handle order:\n%s", output)
+	}
+	if strings.Contains(output, "[fail] ok") {
+		t.Errorf("output reports the destroyed sandbox as failed:\n%s", output)
+	}
+
+	_, err = dyn.Resource(sandboxClaimGVR).Namespace(testNamespace).Get(context.Background(), sandboxName("ok"), metav1.GetOptions{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("SandboxClaim for ok still exists: %v", err)
+	}
+	if _, err := getClaim(context.Background(), client, testNamespace, "locked"); err != nil {
+		t.Errorf("protected sandbox was destroyed: %v", err)
+	}
+}
+
+// A PVC kept by --keep-data is not reattached, so creating the handle again
+// must fail instead of silently starting on an empty workspace.
+func TestKeptPVCBlocksCreate(t *testing.T) {
+	pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Name:            "workspace-" + sandboxName("demo"),
+		Namespace:       testNamespace,
+		OwnerReferences: []metav1.OwnerReference{{Kind: "Sandbox", Name: sandboxName("demo")}},
+	}}
+	claim := testClaim("demo", "pod-demo", false)
+	claim.Object["status"].(map[string]interface{})["sandbox"] = map[string]interface{}{"name": sandboxName("demo")}
+	client := kube.NewClientFrom(newFakeDynamic(claim), fake.NewSimpleClientset(pvc))
+
+	var out bytes.Buffer
+	if err := destroySandbox(context.Background(), client, &out, testNamespace, "demo", destroyOptions{keepData: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	existing, err := getSandboxObjects(context.Background(), client, testNamespace, "demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mode := range []createMode{createNew, createIfNotExists, createAdopt} {
+		err := existing.check("demo", mode)
+		if err == nil || !strings.Contains(err.Error(), pvc.Name) {
+			t.Errorf("mode %d: check = %v, want it to name the kept PVC", mode, err)
+		}
+	}
+
+	other, err := getSandboxObjects(context.Background(), client, testNamespace, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.check("other", createNew); err != nil {
+		t.Errorf("kept PVC of demo blocks creating other: %v", err)
+	}
+}
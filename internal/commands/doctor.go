@@ -0,0 +1,606 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/rathi/agentikube/internal/config"
+	"github.com/rathi/agentikube/internal/kube"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+func NewDoctorCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor <handle>",
+		Short: "Explain why a sandbox is not ready",
+		Long: "Collects the SandboxClaim, pod, PVCs, events, SandboxTemplate, StorageClass\n" +
+			"and Karpenter NodePool and NodeClaims of a sandbox and checks them for known\n" +
+			"problems. Likely causes are printed most likely first, each with the evidence\n" +
+			"it is based on and a suggested fix.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			handle := args[0]
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			client, err := kube.NewClient()
+			if err != nil {
+				return fmt.Errorf("connecting to cluster: %w", err)
+			}
+
+			d, err := collectDiagnosis(ctx, client, cfg, handle)
+			if err != nil {
+				return err
+			}
+			printDiagnosis(os.Stdout, d, diagnose(d))
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// diagnosis is everything doctor knows about a sandbox. Objects that do not
+// exist are nil; lookups that failed for other reasons are listed in errs
+// and marked in unchecked, so a missing object is not mistaken for a cause.
+type diagnosis struct {
+	cfg    *config.Config
+	handle string
+
+	claim        *unstructured.Unstructured
+	template     *unstructured.Unstructured
+	warmPool     *unstructured.Unstructured
+	storageClass bool
+	pod          *corev1.Pod
+	podName      string
+	pvcs         []corev1.PersistentVolumeClaim
+	events       []corev1.Event
+	nodePool     *unstructured.Unstructured
+	nodeClaims   []unstructured.Unstructured
+
+	errs      []string
+	unchecked map[string]bool
+}
+
+// finding is one likely cause of a problem. Higher scores are more likely
+// to be the reason the sandbox is not ready.
+type finding struct {
+	score    int
+	cause    string
+	evidence []string
+	fix      string
+}
+
+func collectDiagnosis(ctx context.Context, client *kube.Client, cfg *config.Config, handle string) (*diagnosis, error) {
+	ns := cfg.Namespace
+	d := &diagnosis{cfg: cfg, handle: handle, unchecked: map[string]bool{}}
+
+	claim, err := getClaim(ctx, client, ns, handle)
+	if err != nil {
+		return nil, err
+	}
+	d.claim = claim
+
+	get := func(what string, fn func() error) {
+		if err := fn(); err != nil && !errors.IsNotFound(err) {
+			d.errs = append(d.errs, fmt.Sprintf("%s: %v", what, err))
+			d.unchecked[what] = true
+		}
+	}
+
+	get("SandboxTemplate", func() error {
+		obj, err := client.Dynamic().Resource(sandboxTemplateGVR).Namespace(ns).Get(ctx, templateName, metav1.GetOptions{})
+		if err == nil {
+			d.template = obj
+		}
+		return err
+	})
+	get("SandboxWarmPool", func() error {
+		obj, err := client.Dynamic().Resource(sandboxWarmPoolGVR).Namespace(ns).Get(ctx, warmPoolName, metav1.GetOptions{})
+		if err == nil {
+			d.warmPool = obj
+		}
+		return err
+	})
+	get("StorageClass", func() error {
+		_, err := client.Clientset().StorageV1().StorageClasses().Get(ctx, storageClassName, metav1.GetOptions{})
+		d.storageClass = err == nil
+		return err
+	})
+
+	d.podName = extractPodName(claim.Object)
+	if d.podName == "-" {
+		// A sandbox started for this claim names its pod after itself.
+		d.podName = extractSandboxName(claim.Object)
+	}
+	get("pod", func() error {
+		pod, err := client.Clientset().CoreV1().Pods(ns).Get(ctx, d.podName, metav1.GetOptions{})
+		if err == nil {
+			d.pod = pod
+		}
+		return err
+	})
+
+	get("PVCs", func() error {
+		pvcs, err := sandboxPVCs(ctx, client, ns, claim)
+		d.pvcs = pvcs
+		return err
+	})
+
+	involved := []struct{ kind, name string }{
+		{"SandboxClaim", claim.GetName()},
+		{"Sandbox", extractSandboxName(claim.Object)},
+		{"Pod", d.podName},
+	}
+	for _, pvc := range d.pvcs {
+		involved = append(involved, struct{ kind, name string }{"PersistentVolumeClaim", pvc.Name})
+	}
+	for _, obj := range involved {
+		get("events for "+obj.kind+" "+obj.name, func() error {
+			events, err := objectEvents(ctx, client, ns, obj.kind, obj.name)
+			d.events = append(d.events, events...)
+			return err
+		})
+	}
+	sortEvents(d.events)
+
+	if cfg.Compute.Type == "karpenter" {
+		get("NodePool", func() error {
+			obj, err := client.Dynamic().Resource(nodePoolGVR).Get(ctx, nodePoolName, metav1.GetOptions{})
+			if err == nil {
+				d.nodePool = obj
+			}
+			return err
+		})
+		get("NodeClaims", func() error {
+			list, err := client.Dynamic().Resource(nodeClaimGVR).List(ctx, metav1.ListOptions{
+				LabelSelector: "karpenter.sh/nodepool=" + nodePoolName,
+			})
+			if err == nil {
+				d.nodeClaims = list.Items
+			}
+			return err
+		})
+	}
+
+	return d, nil
+}
+
+// objectEvents returns the events recorded for one object.
+func objectEvents(ctx context.Context, client *kube.Client, namespace, kind, name string) ([]corev1.Event, error) {
+	list, err := client.Clientset().CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.AndSelectors(
+			fields.OneTermEqualSelector("involvedObject.kind", kind),
+			fields.OneTermEqualSelector("involvedObject.name", name),
+		).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// doctorChecks are the rules doctor runs. Each returns nil when it finds
+// nothing wrong.
+var doctorChecks = []func(d *diagnosis) *finding{
+	checkPaused,
+	checkTemplate,
+	checkStorageClass,
+	checkPVCs,
+	checkImagePull,
+	checkCrashLoop,
+	checkStartupProbe,
+	checkScheduling,
+	checkUnbound,
+}
+
+// diagnose runs every check and returns the findings, most likely first.
+// A ready sandbox has no findings, whatever its event history says.
+func diagnose(d *diagnosis) []finding {
+	if extractStatus(d.claim.Object) == "Ready" {
+		return nil
+	}
+	var findings []finding
+	for _, check := range doctorChecks {
+		if f := check(d); f != nil {
+			findings = append(findings, *f)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].score > findings[j].score
+	})
+	return findings
+}
+
+func checkPaused(d *diagnosis) *finding {
+	if !isPaused(d.claim.Object) {
+		return nil
+	}
+	return &finding{
+		score:    100,
+		cause:    "the sandbox is " + strings.ToLower(extractStatus(d.claim.Object)),
+		evidence: []string{fmt.Sprintf("annotation %s=%s", pausedAnnotation, claimAnnotation(d.claim.Object, pausedAnnotation))},
+		fix:      fmt.Sprintf("run `agentikube resume %s`", d.handle),
+	}
+}
+
+func checkTemplate(d *diagnosis) *finding {
+	if d.template != nil || d.unchecked["SandboxTemplate"] {
+		return nil
+	}
+	evidence := []string{fmt.Sprintf("SandboxTemplate %s not found in namespace %s", templateName, d.cfg.Namespace)}
+	if d.warmPool == nil && !d.unchecked["SandboxWarmPool"] {
+		evidence = append(evidence, fmt.Sprintf("SandboxWarmPool %s not found either, as after `agentikube down`", warmPoolName))
+	}
+	return &finding{
+		score:    95,
+		cause:    "the SandboxTemplate is missing",
+		evidence: evidence,
+		fix:      "run `agentikube up` to recreate the template",
+	}
+}
+
+func checkStorageClass(d *diagnosis) *finding {
+	if d.storageClass || d.unchecked["StorageClass"] {
+		return nil
+	}
+	evidence := []string{fmt.Sprintf("StorageClass %s not found", storageClassName)}
+	for _, pvc := range d.pvcs {
+		if pvc.Spec.StorageClassName != nil && *pvc.Spec.StorageClassName == storageClassName {
+			evidence = append(evidence, fmt.Sprintf("PVC %s uses it and is %s", pvc.Name, pvc.Status.Phase))
+		}
+	}
+	return &finding{
+		score:    90,
+		cause:    "the " + storageClassName + " StorageClass is missing",
+		evidence: evidence,
+		fix:      "run `agentikube up` to recreate it",
+	}
+}
+
+func checkPVCs(d *diagnosis) *finding {
+	var evidence []string
+	for _, pvc := range d.pvcs {
+		if pvc.Status.Phase == corev1.ClaimBound {
+			continue
+		}
+		class := "-"
+		if pvc.Spec.StorageClassName != nil {
+			class = *pvc.Spec.StorageClassName
+		}
+		evidence = append(evidence, fmt.Sprintf("PVC %s is %s (storage class %s)", pvc.Name, pvc.Status.Phase, class))
+		evidence = append(evidence, warningEvents(d, "PersistentVolumeClaim", pvc.Name)...)
+	}
+	if d.pod != nil {
+		evidence = append(evidence, eventsWithReason(d, "FailedMount", "FailedAttachVolume")...)
+	}
+	if len(evidence) == 0 {
+		return nil
+	}
+	return &finding{
+		score:    80,
+		cause:    "the workspace volume is not bound",
+		evidence: evidence,
+		fix:      "check that the EFS CSI driver is installed and storage.filesystemId in the config is correct",
+	}
+}
+
+func checkImagePull(d *diagnosis) *finding {
+	if d.pod == nil {
+		return nil
+	}
+	var evidence []string
+	for _, cs := range containerStatuses(d.pod) {
+		w := cs.State.Waiting
+		if w == nil {
+			continue
+		}
+		switch w.Reason {
+		case "ErrImagePull", "ImagePullBackOff", "InvalidImageName", "ErrImageNeverPull":
+			evidence = append(evidence, fmt.Sprintf("container %s is waiting: %s: %s", cs.Name, w.Reason, w.Message))
+			evidence = append(evidence, "image: "+cs.Image)
+		}
+	}
+	if len(evidence) == 0 {
+		return nil
+	}
+	evidence = append(evidence, lastEvent(eventsWithReason(d, "Failed"))...)
+	return &finding{
+		score:    90,
+		cause:    "the sandbox image cannot be pulled",
+		evidence: evidence,
+		fix:      "check sandbox.image in the config and that the nodes are allowed to pull it",
+	}
+}
+
+func checkCrashLoop(d *diagnosis) *finding {
+	if d.pod == nil {
+		return nil
+	}
+	var evidence []string
+	for _, cs := range containerStatuses(d.pod) {
+		if cs.State.Waiting == nil || cs.State.Waiting.Reason != "CrashLoopBackOff" {
+			continue
+		}
+		line := fmt.Sprintf("container %s restarted %d times", cs.Name, cs.RestartCount)
+		if t := cs.LastTerminationState.Terminated; t != nil {
+			line += fmt.Sprintf(", last exit code %d (%s)", t.ExitCode, t.Reason)
+		}
+		evidence = append(evidence, line)
+	}
+	if len(evidence) == 0 {
+		return nil
+	}
+	return &finding{
+		score:    85,
+		cause:    "the sandbox container keeps crashing",
+		evidence: evidence,
+		fix:      fmt.Sprintf("run `agentikube logs %s --previous` to see why it exits", d.handle),
+	}
+}
+
+func checkStartupProbe(d *diagnosis) *finding {
+	if d.pod == nil {
+		return nil
+	}
+	var failures int32
+	var last string
+	for _, ev := range d.events {
+		if ev.InvolvedObject.Kind == "Pod" && ev.Reason == "Unhealthy" && strings.HasPrefix(ev.Message, "Startup probe") {
+			failures += max(ev.Count, 1)
+			last = strings.TrimSpace(ev.Message)
+		}
+	}
+	if failures == 0 {
+		return nil
+	}
+
+	probes := d.cfg.Sandbox.Probes
+	evidence := []string{
+		fmt.Sprintf("startup probe failed %d times (failure threshold %d): %s", failures, probes.StartupFailureThreshold, last),
+		fmt.Sprintf("the probe connects to TCP port %d (sandbox.probes.port)", probes.Port),
+	}
+	if !slices.Contains(d.cfg.Sandbox.Ports, probes.Port) {
+		evidence = append(evidence, fmt.Sprintf("port %d is not in sandbox.ports %v", probes.Port, d.cfg.Sandbox.Ports))
+	}
+	return &finding{
+		score:    70,
+		cause:    "the startup probe is failing",
+		evidence: evidence,
+		fix:      "make sure the image listens on the probe port, or raise sandbox.probes.startupFailureThreshold if it starts slowly",
+	}
+}
+
+func checkScheduling(d *diagnosis) *finding {
+	if d.pod == nil || d.pod.Spec.NodeName != "" {
+		return nil
+	}
+	var evidence []string
+	for _, c := range d.pod.Status.Conditions {
+		if c.Type == corev1.PodScheduled && c.Status == corev1.ConditionFalse {
+			evidence = append(evidence, fmt.Sprintf("pod %s is %s: %s", d.pod.Name, c.Reason, c.Message))
+		}
+	}
+	if len(evidence) == 0 {
+		return nil
+	}
+	evidence = append(evidence, lastEvent(eventsWithReason(d, "FailedScheduling"))...)
+
+	f := &finding{
+		score:    60,
+		cause:    "the pod cannot be scheduled",
+		evidence: evidence,
+		fix:      "check the node pool and the resources requested in sandbox.resources",
+	}
+
+	for _, nc := range d.nodeClaims {
+		if reason := nodeClaimProblem(&nc); reason != "" {
+			f.evidence = append(f.evidence, fmt.Sprintf("NodeClaim %s: %s", nc.GetName(), reason))
+		}
+	}
+
+	if limits := nodePoolLimitsReached(d); len(limits) > 0 {
+		f.score = 95
+		f.cause = "the Karpenter NodePool has reached its limits"
+		f.evidence = append(limits, f.evidence...)
+		f.fix = "raise compute.maxCpu or compute.maxMemory in the config and run `agentikube up`, or destroy idle sandboxes"
+	}
+	return f
+}
+
+func checkUnbound(d *diagnosis) *finding {
+	if d.pod != nil || d.unchecked["pod"] || isPaused(d.claim.Object) || (d.template == nil && !d.unchecked["SandboxTemplate"]) {
+		return nil
+	}
+	evidence := []string{fmt.Sprintf("SandboxClaim %s is %s and has no pod", d.claim.GetName(), extractStatus(d.claim.Object))}
+	if msg := readyMessage(d.claim); msg != "" {
+		evidence = append(evidence, "Ready condition: "+msg)
+	}
+	if d.warmPool != nil {
+		spec, _ := d.warmPool.Object["spec"].(map[string]interface{})
+		status, _ := d.warmPool.Object["status"].(map[string]interface{})
+		evidence = append(evidence, fmt.Sprintf("warm pool has %d of %d sandboxes ready", getInt64(status, "readyReplicas"), getInt64(spec, "replicas")))
+	}
+	evidence = append(evidence, warningEvents(d, "SandboxClaim", d.claim.GetName())...)
+	evidence = append(evidence, warningEvents(d, "Sandbox", extractSandboxName(d.claim.Object))...)
+	return &finding{
+		score:    40,
+		cause:    "no pod has been started for the claim yet",
+		evidence: evidence,
+		fix:      "check the sandbox controller logs; a new pod is started when the warm pool is empty",
+	}
+}
+
+// nodePoolLimitsReached compares the resources in use by the NodePool with
+// its limits and returns a line for every limit that leaves no room for the
+// sandbox pod.
+func nodePoolLimitsReached(d *diagnosis) []string {
+	if d.nodePool == nil || d.pod == nil {
+		return nil
+	}
+	limits, _, _ := unstructured.NestedMap(d.nodePool.Object, "spec", "limits")
+	used, _, _ := unstructured.NestedMap(d.nodePool.Object, "status", "resources")
+
+	requests, _ := podResources(d.pod)
+
+	var out []string
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		limit, ok := quantity(limits[string(name)])
+		if !ok {
+			continue
+		}
+		inUse, _ := quantity(used[string(name)])
+		want := inUse.DeepCopy()
+		want.Add(requests[name])
+		if want.Cmp(limit) > 0 {
+			req := requests[name]
+			out = append(out, fmt.Sprintf("NodePool %s %s: %s of %s in use, the pod requests %s more",
+				nodePoolName, name, inUse.String(), limit.String(), req.String()))
+		}
+	}
+	return out
+}
+
+// quantity parses a resource quantity from an unstructured value, which may
+// be a string or a number.
+func quantity(v interface{}) (resource.Quantity, bool) {
+	if v == nil {
+		return resource.Quantity{}, false
+	}
+	q, err := resource.ParseQuantity(fmt.Sprint(v))
+	if err != nil {
+		return resource.Quantity{}, false
+	}
+	return q, true
+}
+
+// nodeClaimProblem describes why a NodeClaim has not become a ready node.
+func nodeClaimProblem(nc *unstructured.Unstructured) string {
+	conditions, _, _ := unstructured.NestedSlice(nc.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		condType, _ := cond["type"].(string)
+		condStatus, _ := cond["status"].(string)
+		if condStatus != "False" {
+			continue
+		}
+		switch condType {
+		case "Launched", "Registered", "Initialized":
+			reason, _ := cond["reason"].(string)
+			message, _ := cond["message"].(string)
+			return strings.TrimSpace(fmt.Sprintf("%s=False (%s) %s", condType, reason, message))
+		}
+	}
+	return ""
+}
+
+func readyMessage(obj *unstructured.Unstructured) string {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok || cond["type"] != "Ready" {
+			continue
+		}
+		reason, _ := cond["reason"].(string)
+		message, _ := cond["message"].(string)
+		return strings.TrimSpace(reason + " " + message)
+	}
+	return ""
+}
+
+func containerStatuses(pod *corev1.Pod) []corev1.ContainerStatus {
+	return append(slices.Clone(pod.Status.InitContainerStatuses), pod.Status.ContainerStatuses...)
+}
+
+// warningEvents formats the Warning events recorded for one object.
+func warningEvents(d *diagnosis, kind, name string) []string {
+	var out []string
+	for _, ev := range d.events {
+		if ev.Type == corev1.EventTypeWarning && ev.InvolvedObject.Kind == kind && ev.InvolvedObject.Name == name {
+			out = append(out, formatEvent(&ev))
+		}
+	}
+	return out
+}
+
+// eventsWithReason formats the events with one of the given reasons.
+func eventsWithReason(d *diagnosis, reasons ...string) []string {
+	var out []string
+	for _, ev := range d.events {
+		if slices.Contains(reasons, ev.Reason) {
+			out = append(out, formatEvent(&ev))
+		}
+	}
+	return out
+}
+
+func lastEvent(lines []string) []string {
+	if len(lines) == 0 {
+		return nil
+	}
+	return lines[len(lines)-1:]
+}
+
+func formatEvent(ev *corev1.Event) string {
+	line := fmt.Sprintf("event %s on %s %s: %s", ev.Reason, ev.InvolvedObject.Kind, ev.InvolvedObject.Name, strings.TrimSpace(ev.Message))
+	if ev.Count > 1 {
+		line += fmt.Sprintf(" (x%d)", ev.Count)
+	}
+	return line
+}
+
+func printDiagnosis(w io.Writer, d *diagnosis, findings []finding) {
+	status := extractStatus(d.claim.Object)
+	fmt.Fprintf(w, "sandbox %q (%s): %s\n", d.handle, d.claim.GetName(), status)
+	if d.pod != nil {
+		node := d.pod.Spec.NodeName
+		if node == "" {
+			node = "-"
+		}
+		fmt.Fprintf(w, "  pod:  %s (%s, node %s)\n", d.pod.Name, d.pod.Status.Phase, node)
+	} else {
+		fmt.Fprintln(w, "  pod:  none")
+	}
+	for _, pvc := range d.pvcs {
+		fmt.Fprintf(w, "  pvc:  %s (%s)\n", pvc.Name, pvc.Status.Phase)
+	}
+	for _, e := range d.errs {
+		fmt.Fprintf(w, "[warn] could not get %s\n", e)
+	}
+
+	if len(findings) == 0 {
+		if status == "Ready" {
+			fmt.Fprintln(w, "\n[ok] no problems found")
+		} else {
+			fmt.Fprintf(w, "\nno known problem matched; run `agentikube events %s` and check the sandbox controller logs\n", d.handle)
+		}
+		return
+	}
+
+	fmt.Fprintln(w, "\nlikely causes, most likely first:")
+	for i, f := range findings {
+		fmt.Fprintf(w, "\n%d. %s\n", i+1, f.cause)
+		for _, e := range f.evidence {
+			fmt.Fprintf(w, "   - %s\n", e)
+		}
+		fmt.Fprintf(w, "   fix: %s\n", f.fix)
+	}
+}
@@ -0,0 +1,153 @@
+package commands
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/rathi/agentikube/internal/config"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// healthyDiagnosis returns a diagnosis of a sandbox that is not ready yet
+// but that none of the rules finds anything wrong with.
+func healthyDiagnosis() *diagnosis {
+	cfg := &config.Config{Namespace: testNamespace}
+	cfg.Sandbox.Ports = []int{8080}
+	cfg.Sandbox.Probes = config.ProbesConfig{Port: 8080, StartupFailureThreshold: 30}
+
+	pod := testPod("sandbox-demo", "500m", "1", "512Mi", "1Gi")
+	pod.Spec.NodeName = "node-1"
+
+	return &diagnosis{
+		cfg:          cfg,
+		handle:       "demo",
+		claim:        testClaim("demo", "sandbox-demo", false),
+		template:     &unstructured.Unstructured{},
+		warmPool:     &unstructured.Unstructured{},
+		storageClass: true,
+		pod:          pod,
+		podName:      "sandbox-demo",
+		unchecked:    map[string]bool{},
+	}
+}
+
+func waitingContainer(reason string) corev1.ContainerStatus {
+	return corev1.ContainerStatus{
+		Name:  "sandbox",
+		Image: "example/sandbox:latest",
+		State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason}},
+	}
+}
+
+func podEvent(reason, message string) corev1.Event {
+	return corev1.Event{
+		Type:           corev1.EventTypeWarning,
+		Reason:         reason,
+		Message:        message,
+		Count:          1,
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "sandbox-demo"},
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	const (
+		paused       = "the sandbox is paused"
+		template     = "the SandboxTemplate is missing"
+		storage      = "the " + storageClassName + " StorageClass is missing"
+		volume       = "the workspace volume is not bound"
+		imagePull    = "the sandbox image cannot be pulled"
+		crashLoop    = "the sandbox container keeps crashing"
+		startupProbe = "the startup probe is failing"
+		scheduling   = "the pod cannot be scheduled"
+		nodePoolFull = "the Karpenter NodePool has reached its limits"
+		unbound      = "no pod has been started for the claim yet"
+	)
+	unschedulable := func(d *diagnosis) {
+		d.pod.Spec.NodeName = ""
+		d.pod.Status.Conditions = []corev1.PodCondition{{
+			Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: "Unschedulable", Message: "0/3 nodes are available",
+		}}
+	}
+	probeFailing := func(d *diagnosis) {
+		d.events = append(d.events, podEvent("Unhealthy", "Startup probe failed: dial tcp 10.0.0.1:8080: connect: connection refused"))
+	}
+
+	tests := []struct {
+		name  string
+		setup func(d *diagnosis)
+		want  []string
+	}{
+		{"healthy", func(d *diagnosis) {}, nil},
+		{"paused", func(d *diagnosis) {
+			d.claim = testClaim("demo", "sandbox-demo", true)
+		}, []string{paused}},
+		{"template missing", func(d *diagnosis) {
+			d.template = nil
+		}, []string{template}},
+		{"storage class missing", func(d *diagnosis) {
+			d.storageClass = false
+		}, []string{storage}},
+		{"volume not bound", func(d *diagnosis) {
+			class := storageClassName
+			d.pvcs = []corev1.PersistentVolumeClaim{{Spec: corev1.PersistentVolumeClaimSpec{StorageClassName: &class}}}
+			d.pvcs[0].Name = "workspace-sandbox-demo"
+			d.pvcs[0].Status.Phase = corev1.ClaimPending
+		}, []string{volume}},
+		{"image pull", func(d *diagnosis) {
+			d.pod.Status.ContainerStatuses = []corev1.ContainerStatus{waitingContainer("ImagePullBackOff")}
+		}, []string{imagePull}},
+		{"crash loop", func(d *diagnosis) {
+			d.pod.Status.ContainerStatuses = []corev1.ContainerStatus{waitingContainer("CrashLoopBackOff")}
+		}, []string{crashLoop}},
+		{"startup probe", probeFailing, []string{startupProbe}},
+		{"unschedulable", unschedulable, []string{scheduling}},
+		{"node pool full", func(d *diagnosis) {
+			unschedulable(d)
+			d.nodePool = &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec":   map[string]interface{}{"limits": map[string]interface{}{"cpu": "16"}},
+				"status": map[string]interface{}{"resources": map[string]interface{}{"cpu": "16"}},
+			}}
+		}, []string{nodePoolFull}},
+		{"no pod", func(d *diagnosis) {
+			d.pod = nil
+		}, []string{unbound}},
+		{"ranked by likelihood", func(d *diagnosis) {
+			unschedulable(d)
+			probeFailing(d)
+			d.storageClass = false
+			d.template = nil
+		}, []string{template, storage, startupProbe, scheduling}},
+		{"paused ranks first", func(d *diagnosis) {
+			d.claim = testClaim("demo", "sandbox-demo", true)
+			d.pod.Status.ContainerStatuses = []corev1.ContainerStatus{waitingContainer("CrashLoopBackOff")}
+		}, []string{paused, crashLoop}},
+		{"lookups failed", func(d *diagnosis) {
+			// A Forbidden lookup says nothing about whether the object exists.
+			d.template = nil
+			d.warmPool = nil
+			d.storageClass = false
+			d.unchecked["SandboxTemplate"] = true
+			d.unchecked["SandboxWarmPool"] = true
+			d.unchecked["StorageClass"] = true
+		}, nil},
+		{"ready", func(d *diagnosis) {
+			d.template = nil
+			d.claim.Object["status"].(map[string]interface{})["conditions"] = []interface{}{
+				map[string]interface{}{"type": "Ready", "status": "True"},
+			}
+		}, nil},
+	}
+	for _, tt := range tests {
+		d := healthyDiagnosis()
+		tt.setup(d)
+
+		var got []string
+		for _, f := range diagnose(d) {
+			got = append(got, f.cause)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: diagnose = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -29,14 +29,14 @@ func NewDownCmd() *cobra.Command {
 
 			ns := cfg.Namespace
 
-			err = client.Dynamic().Resource(sandboxWarmPoolGVR).Namespace(ns).Delete(ctx, "sandbox-warm-pool", metav1.DeleteOptions{})
+			err = client.Dynamic().Resource(sandboxWarmPoolGVR).Namespace(ns).Delete(ctx, warmPoolName, metav1.DeleteOptions{})
 			if err != nil {
 				fmt.Printf("[warn] could not delete SandboxWarmPool: %v\n", err)
 			} else {
 				fmt.Println("[ok] SandboxWarmPool deleted")
 			}
 
-			err = client.Dynamic().Resource(sandboxTemplateGVR).Namespace(ns).Delete(ctx, "sandbox-template", metav1.DeleteOptions{})
+			err = client.Dynamic().Resource(sandboxTemplateGVR).Namespace(ns).Delete(ctx, templateName, metav1.DeleteOptions{})
 			if err != nil {
 				fmt.Printf("[warn] could not delete SandboxTemplate: %v\n", err)
 			} else {
@@ -0,0 +1,356 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rathi/agentikube/internal/kube"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// eventKinds maps the values of `events --kind` to the kinds of the objects
+// whose events they select.
+var eventKinds = map[string][]string{
+	"sandbox":  {"SandboxClaim", "Sandbox"},
+	"warmpool": {"SandboxWarmPool"},
+	"pod":      {"Pod"},
+	"pvc":      {"PersistentVolumeClaim"},
+	"node":     {"Node", "NodeClaim", "NodePool"},
+}
+
+// Owners that events are attributed to when they do not belong to a handle.
+const (
+	warmPoolOwner  = "warm-pool"
+	karpenterOwner = "karpenter"
+)
+
+// ownerRefresh limits how often the owner index is rebuilt when events for
+// unknown objects arrive, e.g. while new sandboxes are created.
+const ownerRefresh = 2 * time.Second
+
+func NewEventsCmd() *cobra.Command {
+	var kinds []string
+	var eventType string
+	var follow bool
+
+	cmd := &cobra.Command{
+		Use:   "events [handle...]",
+		Short: "Show events of sandboxes, the warm pool and nodes",
+		Long: "Prints the Kubernetes events of everything agentikube manages: SandboxClaims\n" +
+			"and Sandboxes, the warm pool, sandbox pods and PVCs, and Karpenter nodes. Each\n" +
+			"event is attributed to the handle it belongs to, or to warm-pool or karpenter.\n" +
+			"Events of other objects in the namespace are left out.\n\n" +
+			"Filter by handle with arguments, by object with --kind (sandbox, warmpool,\n" +
+			"pod, pvc, node) and by --type (Normal or Warning). With --follow new events\n" +
+			"are printed as they happen.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			filter := eventFilter{handles: args}
+			for _, k := range kinds {
+				objKinds, ok := eventKinds[strings.ToLower(k)]
+				if !ok {
+					return fmt.Errorf("unknown --kind %q: expected sandbox, warmpool, pod, pvc or node", k)
+				}
+				filter.kinds = append(filter.kinds, objKinds...)
+			}
+			switch strings.ToLower(eventType) {
+			case "":
+			case "normal":
+				filter.eventType = corev1.EventTypeNormal
+			case "warning":
+				filter.eventType = corev1.EventTypeWarning
+			default:
+				return fmt.Errorf("unknown --type %q: expected Normal or Warning", eventType)
+			}
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			client, err := kube.NewClient()
+			if err != nil {
+				return fmt.Errorf("connecting to cluster: %w", err)
+			}
+
+			owners := &eventOwners{
+				client:    client,
+				namespace: cfg.Namespace,
+				karpenter: cfg.Compute.Type == "karpenter",
+			}
+			if err := owners.refresh(ctx); err != nil {
+				return err
+			}
+
+			if follow {
+				return followEvents(ctx, client, os.Stdout, owners, filter)
+			}
+			return printEvents(ctx, client, os.Stdout, owners, filter)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&kinds, "kind", nil, "only show events of these objects: sandbox, warmpool, pod, pvc, node")
+	cmd.Flags().StringVar(&eventType, "type", "", "only show events of this type: Normal or Warning")
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "keep printing new events")
+
+	return cmd
+}
+
+// eventFilter selects events by owner handle, object kind and type. Empty
+// fields match everything.
+type eventFilter struct {
+	handles   []string
+	kinds     []string
+	eventType string
+}
+
+func (f eventFilter) match(ev *corev1.Event, owner string) bool {
+	if len(f.handles) > 0 && !slices.Contains(f.handles, owner) {
+		return false
+	}
+	if len(f.kinds) > 0 && !slices.Contains(f.kinds, ev.InvolvedObject.Kind) {
+		return false
+	}
+	return f.eventType == "" || ev.Type == f.eventType
+}
+
+// eventOwners maps the objects agentikube manages to the handle they belong
+// to, so events can be reported per sandbox rather than per pod or PVC.
+type eventOwners struct {
+	client    *kube.Client
+	namespace string
+	karpenter bool
+
+	owners  map[string]string
+	updated time.Time
+}
+
+func ownerKey(kind, name string) string {
+	return kind + "/" + name
+}
+
+// refresh rebuilds the index from the current SandboxClaims, Sandboxes,
+// PVCs and, with Karpenter, the nodes of the sandbox NodePool.
+func (o *eventOwners) refresh(ctx context.Context) error {
+	owners := map[string]string{
+		ownerKey("SandboxWarmPool", warmPoolName): warmPoolOwner,
+	}
+	o.updated = time.Now()
+
+	claims, err := o.client.Dynamic().Resource(sandboxClaimGVR).Namespace(o.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("listing SandboxClaims: %w", err)
+	}
+	for _, claim := range claims.Items {
+		handle := handleFromClaim(&claim)
+		sandbox := extractSandboxName(claim.Object)
+		owners[ownerKey("SandboxClaim", claim.GetName())] = handle
+		owners[ownerKey("Sandbox", sandbox)] = handle
+		owners[ownerKey("Pod", sandbox)] = handle
+		owners[ownerKey("PersistentVolumeClaim", claim.GetName())] = handle
+		if pod := extractPodName(claim.Object); pod != "-" {
+			owners[ownerKey("Pod", pod)] = handle
+		}
+	}
+
+	// Sandboxes nobody claimed yet belong to the warm pool.
+	sandboxes, err := o.client.Dynamic().Resource(sandboxGVR).Namespace(o.namespace).List(ctx, metav1.ListOptions{})
+	if err == nil {
+		for _, sb := range sandboxes.Items {
+			if _, ok := owners[ownerKey("Sandbox", sb.GetName())]; ok {
+				continue
+			}
+			for _, ref := range sb.GetOwnerReferences() {
+				if ref.Kind == "SandboxWarmPool" {
+					owners[ownerKey("Sandbox", sb.GetName())] = warmPoolOwner
+					owners[ownerKey("Pod", sb.GetName())] = warmPoolOwner
+				}
+			}
+		}
+	}
+
+	pvcs, err := o.client.Clientset().CoreV1().PersistentVolumeClaims(o.namespace).List(ctx, metav1.ListOptions{})
+	if err == nil {
+		for _, pvc := range pvcs.Items {
+			for _, ref := range pvc.OwnerReferences {
+				if owner, ok := owners[ownerKey("Sandbox", ref.Name)]; ok && ref.Kind == "Sandbox" {
+					owners[ownerKey("PersistentVolumeClaim", pvc.Name)] = owner
+				}
+			}
+		}
+	}
+
+	if o.karpenter {
+		owners[ownerKey("NodePool", nodePoolName)] = karpenterOwner
+		selector := "karpenter.sh/nodepool=" + nodePoolName
+		nodes, err := o.client.Clientset().CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err == nil {
+			for _, node := range nodes.Items {
+				owners[ownerKey("Node", node.Name)] = karpenterOwner
+			}
+		}
+		nodeClaims, err := o.client.Dynamic().Resource(nodeClaimGVR).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err == nil {
+			for _, nc := range nodeClaims.Items {
+				owners[ownerKey("NodeClaim", nc.GetName())] = karpenterOwner
+			}
+		}
+	}
+
+	o.owners = owners
+	return nil
+}
+
+// lookup returns the owner of the object an event is about. Unknown objects
+// trigger a rebuild of the index, at most once per ownerRefresh.
+func (o *eventOwners) lookup(ctx context.Context, ev *corev1.Event) (string, bool) {
+	key := ownerKey(ev.InvolvedObject.Kind, ev.InvolvedObject.Name)
+	if owner, ok := o.owners[key]; ok {
+		return owner, true
+	}
+	if time.Since(o.updated) < ownerRefresh {
+		return "", false
+	}
+	if err := o.refresh(ctx); err != nil {
+		return "", false
+	}
+	owner, ok := o.owners[key]
+	return owner, ok
+}
+
+// eventNamespaces returns the namespaces events are read from. Events of
+// cluster-scoped objects such as nodes are recorded in the default
+// namespace.
+func (o *eventOwners) eventNamespaces() []string {
+	if o.karpenter && o.namespace != metav1.NamespaceDefault {
+		return []string{o.namespace, metav1.NamespaceDefault}
+	}
+	return []string{o.namespace}
+}
+
+// printEvents prints the matching events recorded so far, oldest first.
+func printEvents(ctx context.Context, client *kube.Client, w io.Writer, owners *eventOwners, filter eventFilter) error {
+	var events []corev1.Event
+	for _, ns := range owners.eventNamespaces() {
+		list, err := client.Clientset().CoreV1().Events(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("listing events in %s: %w", ns, err)
+		}
+		events = append(events, list.Items...)
+	}
+	sortEvents(events)
+
+	printed := 0
+	for i := range events {
+		if printEvent(ctx, w, owners, filter, &events[i]) {
+			printed++
+		}
+	}
+	if printed == 0 {
+		fmt.Fprintln(w, "no events found")
+	}
+	return nil
+}
+
+// followEvents prints the matching events recorded so far and then every
+// new or repeated event until ctx is done.
+func followEvents(ctx context.Context, client *kube.Client, w io.Writer, owners *eventOwners, filter eventFilter) error {
+	received := make(chan *corev1.Event, 256)
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if ev, ok := obj.(*corev1.Event); ok {
+				received <- ev
+			}
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			if ev, ok := obj.(*corev1.Event); ok {
+				received <- ev
+			}
+		},
+	}
+
+	var synced []cache.InformerSynced
+	var stores []cache.Store
+	for _, ns := range owners.eventNamespaces() {
+		factory := informers.NewSharedInformerFactoryWithOptions(client.Clientset(), 0, informers.WithNamespace(ns))
+		informer := factory.Core().V1().Events().Informer()
+		if _, err := informer.AddEventHandler(handler); err != nil {
+			return err
+		}
+		factory.Start(ctx.Done())
+		synced = append(synced, informer.HasSynced)
+		stores = append(stores, informer.GetStore())
+	}
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+		return nil
+	}
+
+	// seen holds the count last printed for each event; repeated events are
+	// updates of the same object with a higher count.
+	seen := map[types.UID]int32{}
+
+	var initial []corev1.Event
+	for _, store := range stores {
+		for _, obj := range store.List() {
+			if ev, ok := obj.(*corev1.Event); ok {
+				initial = append(initial, *ev)
+			}
+		}
+	}
+	sortEvents(initial)
+	for i := range initial {
+		seen[initial[i].UID] = initial[i].Count
+		printEvent(ctx, w, owners, filter, &initial[i])
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev := <-received:
+			if count, ok := seen[ev.UID]; ok && count >= ev.Count {
+				continue
+			}
+			seen[ev.UID] = ev.Count
+			printEvent(ctx, w, owners, filter, ev)
+		}
+	}
+}
+
+// printEvent prints ev as "<time> <type> <owner>: <reason> <kind>/<name>:
+// <message>" if it belongs to agentikube and matches filter.
+func printEvent(ctx context.Context, w io.Writer, owners *eventOwners, filter eventFilter, ev *corev1.Event) bool {
+	owner, ok := owners.lookup(ctx, ev)
+	if !ok || !filter.match(ev, owner) {
+		return false
+	}
+
+	line := fmt.Sprintf("%s  %-7s  %s: %s %s/%s: %s",
+		eventTime(ev).UTC().Format(time.RFC3339), ev.Type, owner, ev.Reason,
+		strings.ToLower(ev.InvolvedObject.Kind), ev.InvolvedObject.Name, strings.TrimSpace(ev.Message))
+	if ev.Count > 1 {
+		line += fmt.Sprintf(" (x%d)", ev.Count)
+	}
+	fmt.Fprintln(w, line)
+	return true
+}
+
+func sortEvents(events []corev1.Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return eventTime(&events[i]).Before(eventTime(&events[j]))
+	})
+}
@@ -0,0 +1,220 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rathi/agentikube/internal/kube"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func NewExecCmd() *cobra.Command {
+	var stdin bool
+	var selector string
+	var parallel int
+
+	cmd := &cobra.Command{
+		Use:   "exec [handle...] -- <command> [args...]",
+		Short: "Run a command in one or more sandboxes",
+		Long: "Runs a command without a TTY in the sandbox pod for each handle and exits with\n" +
+			"the remote exit status. With several handles, or with --selector, the command\n" +
+			"runs concurrently and each output line is prefixed with its handle.",
+		Args: func(cmd *cobra.Command, args []string) error {
+			dash := cmd.ArgsLenAtDash()
+			if dash < 0 || dash == len(args) {
+				return fmt.Errorf("a command is required after --")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			dash := cmd.ArgsLenAtDash()
+			// Clipped so appending selector matches cannot overwrite command.
+			handles, command := slices.Clip(args[:dash]), args[dash:]
+			if len(handles) == 0 && selector == "" {
+				return fmt.Errorf("specify at least one handle or --selector")
+			}
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			client, err := kube.NewClient()
+			if err != nil {
+				return fmt.Errorf("connecting to cluster: %w", err)
+			}
+
+			ns := cfg.Namespace
+
+			if selector != "" {
+				list, err := client.Dynamic().Resource(sandboxClaimGVR).Namespace(ns).List(ctx, metav1.ListOptions{
+					LabelSelector: selector,
+				})
+				if err != nil {
+					return fmt.Errorf("listing SandboxClaims: %w", err)
+				}
+				for _, item := range list.Items {
+					handles = append(handles, handleFromClaim(&item))
+				}
+				if len(handles) == 0 {
+					return fmt.Errorf("no sandboxes match selector %q", selector)
+				}
+			}
+			// A handle given twice, or both as an argument and through the
+			// selector, runs once.
+			handles = uniqueHandles(handles)
+
+			if len(handles) == 1 && selector == "" {
+				if err := wakeIfHibernated(ctx, client, ns, handles[0], "exec"); err != nil {
+					return err
+				}
+				podName, err := resolvePod(ctx, client, ns, handles[0])
+				if err != nil {
+					return err
+				}
+				keepActive(ctx, client, ns, handles[0])
+				opts := kube.ExecOptions{
+					Namespace: ns,
+					Pod:       podName,
+					Command:   command,
+					Stdout:    os.Stdout,
+					Stderr:    os.Stderr,
+				}
+				if stdin {
+					opts.Stdin = os.Stdin
+				}
+				return client.Exec(ctx, opts)
+			}
+
+			if stdin {
+				return fmt.Errorf("--stdin can only be used with a single handle")
+			}
+			return execMany(ctx, client, ns, handles, command, parallel)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&stdin, "stdin", "i", false, "forward local stdin to the command")
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "run in every sandbox matching this label selector")
+	cmd.Flags().IntVar(&parallel, "parallel", 5, "maximum number of sandboxes to run in at once")
+
+	return cmd
+}
+
+// execMany runs command in every handle's pod with at most parallel sessions
+// in flight. Output lines are prefixed with the handle. It returns an
+// *kube.ExitError with the highest exit status when any handle fails.
+func execMany(ctx context.Context, client *kube.Client, namespace string, handles []string, command []string, parallel int) error {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	width := 0
+	for _, h := range handles {
+		width = max(width, len(h))
+	}
+
+	var outMu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, parallel)
+	codes := make(map[string]int, len(handles))
+	var codesMu sync.Mutex
+
+	for _, handle := range handles {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			prefix := fmt.Sprintf("%-*s | ", width, handle)
+			stdout := newPrefixWriter(&outMu, os.Stdout, prefix)
+			stderr := newPrefixWriter(&outMu, os.Stderr, prefix)
+
+			code := execOne(ctx, client, namespace, handle, command, stdout, stderr)
+			stdout.Flush()
+			stderr.Flush()
+
+			codesMu.Lock()
+			codes[handle] = code
+			codesMu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	var failed []string
+	worst := 0
+	for handle, code := range codes {
+		if code != 0 {
+			failed = append(failed, fmt.Sprintf("%s (exit %d)", handle, code))
+			worst = max(worst, code)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	sort.Strings(failed)
+	fmt.Fprintf(os.Stderr, "\n%d of %d sandboxes failed: %s\n", len(failed), len(handles), strings.Join(failed, ", "))
+	return &kube.ExitError{Code: worst}
+}
+
+// uniqueHandles returns handles without repeats, in first-seen order.
+func uniqueHandles(handles []string) []string {
+	seen := make(map[string]bool, len(handles))
+	out := make([]string, 0, len(handles))
+	for _, h := range handles {
+		if !seen[h] {
+			seen[h] = true
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+// execOne runs command in a single handle's pod and returns its exit status.
+// Errors that prevent the command from running are written to stderr and
+// reported as status 1.
+func execOne(ctx context.Context, client *kube.Client, namespace, handle string, command []string, stdout, stderr io.Writer) int {
+	if err := wakeIfHibernated(ctx, client, namespace, handle, "exec"); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	podName, err := resolvePod(ctx, client, namespace, handle)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	keepActive(ctx, client, namespace, handle)
+
+	err = client.Exec(ctx, kube.ExecOptions{
+		Namespace: namespace,
+		Pod:       podName,
+		Command:   command,
+		Stdout:    stdout,
+		Stderr:    stderr,
+	})
+	var exitErr *kube.ExitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exitErr):
+		return exitErr.Code
+	default:
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+}
@@ -0,0 +1,21 @@
+package commands
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestUniqueHandles(t *testing.T) {
+	tests := []struct {
+		in, want []string
+	}{
+		{nil, []string{}},
+		{[]string{"a"}, []string{"a"}},
+		{[]string{"b", "a", "b", "c", "a"}, []string{"b", "a", "c"}},
+	}
+	for _, tt := range tests {
+		if got := uniqueHandles(tt.in); !slices.Equal(got, tt.want) {
+			t.Errorf("uniqueHandles(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -1,9 +1,23 @@
 package commands
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/rathi/agentikube/internal/config"
+	"github.com/rathi/agentikube/internal/kube"
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 var (
@@ -22,8 +36,79 @@ var (
 		Version:  "v1alpha1",
 		Resource: "sandboxwarmpools",
 	}
+	sandboxGVR = schema.GroupVersionResource{
+		Group:    "agents.x-k8s.io",
+		Version:  "v1alpha1",
+		Resource: "sandboxes",
+	}
+	nodePoolGVR = schema.GroupVersionResource{
+		Group:    "karpenter.sh",
+		Version:  "v1",
+		Resource: "nodepools",
+	}
+	nodeClaimGVR = schema.GroupVersionResource{
+		Group:    "karpenter.sh",
+		Version:  "v1",
+		Resource: "nodeclaims",
+	}
+)
+
+// Names of the shared resources created by `agentikube up`.
+const (
+	templateName     = "sandbox-template"
+	warmPoolName     = "sandbox-warm-pool"
+	storageClassName = "efs-sandbox"
+	nodePoolName     = "sandbox-pool"
+)
+
+// Annotations and labels agentikube records on SandboxClaims.
+const (
+	// pausedAnnotation is set while the sandbox is scaled to zero. The value
+	// is the RFC 3339 time the sandbox was paused.
+	pausedAnnotation = "agentikube.io/paused-at"
+
+	// actionAnnotation, actionReasonAnnotation and actionTimeAnnotation
+	// record the last lifecycle action taken on the sandbox and why.
+	actionAnnotation       = "agentikube.io/last-action"
+	actionReasonAnnotation = "agentikube.io/last-action-reason"
+	actionTimeAnnotation   = "agentikube.io/last-action-at"
+
+	// activityAnnotation is refreshed by interactive commands (ssh, exec,
+	// cp, port-forward) so the reaper can tell the sandbox is in use.
+	activityAnnotation = "agentikube.io/last-activity"
+
+	// cpuSampleAnnotation holds the reaper's last cgroup CPU reading as
+	// "<usage-usec>@<unix-seconds>".
+	cpuSampleAnnotation = "agentikube.io/cpu-sample"
+
+	// readyAfterAnnotation records how long create took until the sandbox
+	// was ready, as a Go duration.
+	readyAfterAnnotation = "agentikube.io/ready-after"
+
+	// handleAnnotation records the handle a sandbox was created for, since
+	// long handles are hashed into the resource name. handleLabel carries
+	// the same value when it fits in a label, for use in selectors.
+	handleAnnotation = "agentikube.io/handle"
+	handleLabel      = "agentikube.io/handle"
+
+	// descriptionAnnotation holds the free-form description given to create.
+	// Unlike the annotations above it is user metadata and is copied to the
+	// Secret and pod.
+	descriptionAnnotation = "agentikube.io/description"
+)
+
+// Lifecycle actions recorded in actionAnnotation.
+const (
+	actionPause     = "pause"
+	actionResume    = "resume"
+	actionHibernate = "hibernate"
+	actionWake      = "wake"
 )
 
+// errNoPod is returned by resolvePod when the claim exists but has not been
+// bound to a pod yet.
+var errNoPod = errors.New("does not have a pod assigned yet")
+
 func coreGVR(resource string) schema.GroupVersionResource {
 	return schema.GroupVersionResource{Group: "", Version: "v1", Resource: resource}
 }
@@ -32,3 +117,230 @@ func loadConfig(cmd *cobra.Command) (*config.Config, error) {
 	cfgPath, _ := cmd.Flags().GetString("config")
 	return config.Load(cfgPath)
 }
+
+// maxNameLength is the longest sandbox resource name. Names end up in pod
+// and Service names and label values, which are limited to 63 characters.
+const maxNameLength = 63
+
+// maxHandleLength caps handles at the length of a DNS-1123 subdomain.
+const maxHandleLength = 253
+
+// validateHandle checks that handle is usable as (part of) a Kubernetes name:
+// lowercase letters, digits and '-', starting and ending with a letter or
+// digit.
+func validateHandle(handle string) error {
+	if handle == "" {
+		return fmt.Errorf("handle must not be empty")
+	}
+	if len(handle) > maxHandleLength {
+		return fmt.Errorf("handle is %d characters long; the maximum is %d", len(handle), maxHandleLength)
+	}
+	if lower := strings.ToLower(handle); lower != handle {
+		return fmt.Errorf("handle %q must be lowercase (try %q)", handle, lower)
+	}
+	for _, r := range handle {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return fmt.Errorf("handle %q contains %q; use only lowercase letters, digits and '-'", handle, r)
+		}
+	}
+	if handle[0] == '-' || handle[len(handle)-1] == '-' {
+		return fmt.Errorf("handle %q must start and end with a letter or digit", handle)
+	}
+	return nil
+}
+
+// sandboxName returns the name of the Secret and SandboxClaim for handle:
+// "sandbox-<handle>", or for handles that would not fit in maxNameLength, a
+// truncated prefix followed by a hash of the full handle.
+func sandboxName(handle string) string {
+	name := "sandbox-" + handle
+	if len(name) <= maxNameLength {
+		return name
+	}
+	sum := sha256.Sum256([]byte(handle))
+	hash := hex.EncodeToString(sum[:])[:8]
+	prefix := strings.TrimRight(name[:maxNameLength-len(hash)-1], "-")
+	return prefix + "-" + hash
+}
+
+// handleFromClaim returns the handle of a SandboxClaim as recorded in
+// handleAnnotation, falling back to the claim name for claims created before
+// the annotation existed.
+func handleFromClaim(claim *unstructured.Unstructured) string {
+	if handle := claim.GetAnnotations()[handleAnnotation]; handle != "" {
+		return handle
+	}
+	if handle := claim.GetLabels()[handleLabel]; handle != "" {
+		return handle
+	}
+	name := claim.GetName()
+	if len(name) > 8 && name[:8] == "sandbox-" {
+		return name[8:]
+	}
+	return name
+}
+
+// getClaim returns the SandboxClaim for handle. Hashed names are checked
+// against the recorded handle so that a collision is reported rather than
+// acting on another sandbox.
+func getClaim(ctx context.Context, client *kube.Client, namespace, handle string) (*unstructured.Unstructured, error) {
+	name := sandboxName(handle)
+	claim, err := client.Dynamic().Resource(sandboxClaimGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("getting SandboxClaim %q: %w", name, err)
+	}
+	if got := handleFromClaim(claim); got != handle {
+		return nil, fmt.Errorf("SandboxClaim %q belongs to handle %q, not %q", name, got, handle)
+	}
+	return claim, nil
+}
+
+// resolvePod returns the name of the pod bound to the SandboxClaim for handle.
+func resolvePod(ctx context.Context, client *kube.Client, namespace, handle string) (string, error) {
+	claim, err := getClaim(ctx, client, namespace, handle)
+	if err != nil {
+		return "", err
+	}
+
+	if isPaused(claim.Object) {
+		return "", fmt.Errorf("sandbox %q is paused (run: agentikube resume %s)", handle, handle)
+	}
+
+	podName := extractPodName(claim.Object)
+	if podName == "-" || podName == "" {
+		return "", fmt.Errorf("sandbox %q %w", handle, errNoPod)
+	}
+	return podName, nil
+}
+
+// waitForPod polls the SandboxClaim for handle until it is bound to a pod,
+// then returns the pod name.
+func waitForPod(ctx context.Context, client *kube.Client, namespace, handle string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	waiting := false
+	for {
+		podName, err := resolvePod(ctx, client, namespace, handle)
+		if err == nil {
+			return podName, nil
+		}
+		if !errors.Is(err, errNoPod) {
+			return "", err
+		}
+		if !waiting {
+			fmt.Printf("waiting for sandbox %q to get a pod...\n", handle)
+			waiting = true
+		}
+		if !sleepCtx(ctx, 2*time.Second) {
+			return "", fmt.Errorf("timed out waiting for sandbox %q to get a pod", handle)
+		}
+	}
+}
+
+// sleepCtx waits for d or until ctx is done. It reports whether the full
+// duration elapsed.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// podRef identifies one incarnation of a sandbox pod. A recreated pod may
+// reuse the old name, so the UID is what tells them apart.
+type podRef struct {
+	name string
+	uid  types.UID
+}
+
+// boundPod returns the pod claim is bound to, or nil if it has no pod or the
+// pod no longer exists.
+func boundPod(ctx context.Context, client *kube.Client, namespace string, claim *unstructured.Unstructured) (*corev1.Pod, error) {
+	podName := extractPodName(claim.Object)
+	if podName == "-" || podName == "" {
+		return nil, nil
+	}
+	pod, err := client.Clientset().CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting pod %s: %w", podName, err)
+	}
+	return pod, nil
+}
+
+// currentPod returns a podRef for the pod claim is bound to, or a zero
+// podRef if there is none. Record it before scaling up or deleting the pod
+// and pass it to waitForNewPod.
+func currentPod(ctx context.Context, client *kube.Client, namespace string, claim *unstructured.Unstructured) (podRef, error) {
+	pod, err := boundPod(ctx, client, namespace, claim)
+	if err != nil || pod == nil {
+		return podRef{}, err
+	}
+	return podRef{name: pod.Name, uid: pod.UID}, nil
+}
+
+// waitForNewPod waits until the claim for handle is bound to a ready pod
+// other than old, then until the claim reports Ready, and returns the new
+// pod name. A paused or restarted claim keeps its last Ready condition, so
+// waiting on the claim alone would return before the new pod is up.
+func waitForNewPod(ctx context.Context, client *kube.Client, namespace, handle string, old podRef, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	state := "no pod yet"
+	for {
+		claim, err := getClaim(ctx, client, namespace, handle)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", fmt.Errorf("timed out waiting for sandbox %q to start a new pod (%s)", handle, state)
+			}
+			return "", err
+		}
+		if isPaused(claim.Object) {
+			return "", fmt.Errorf("sandbox %q was paused while waiting for it", handle)
+		}
+		pod, err := boundPod(ctx, client, namespace, claim)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", fmt.Errorf("timed out waiting for sandbox %q to start a new pod (%s)", handle, state)
+			}
+			return "", err
+		}
+
+		switch {
+		case pod == nil:
+			state = "no pod yet"
+		case pod.UID == old.uid:
+			state = fmt.Sprintf("old pod %s is still bound", old.name)
+		case pod.DeletionTimestamp != nil:
+			state = fmt.Sprintf("pod %s is terminating", pod.Name)
+		case !podReady(pod):
+			state = fmt.Sprintf("pod %s is not ready", pod.Name)
+		default:
+			if err := client.WaitForReady(ctx, namespace, sandboxClaimGVR, claim.GetName()); err != nil {
+				return "", fmt.Errorf("waiting for sandbox %q: %w", handle, err)
+			}
+			return pod.Name, nil
+		}
+
+		if !sleepCtx(ctx, 2*time.Second) {
+			return "", fmt.Errorf("timed out waiting for sandbox %q to start a new pod (%s)", handle, state)
+		}
+	}
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rathi/agentikube/internal/kube"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestValidateHandle(t *testing.T) {
+	tests := []struct {
+		handle string
+		ok     bool
+	}{
+		{"demo", true},
+		{"a", true},
+		{"0", true},
+		{"eng-42-fix", true},
+		{"a--b", true},
+		{strings.Repeat("a", maxHandleLength), true},
+		{strings.Repeat("a", maxHandleLength+1), false},
+		{"", false},
+		{"Demo", false},
+		{"demo_1", false},
+		{"demo.1", false},
+		{"demo 1", false},
+		{"dé", false},
+		{"-demo", false},
+		{"demo-", false},
+		{"-", false},
+	}
+	for _, tt := range tests {
+		err := validateHandle(tt.handle)
+		if (err == nil) != tt.ok {
+			t.Errorf("validateHandle(%q) = %v, want ok=%v", tt.handle, err, tt.ok)
+		}
+	}
+}
+
+func TestSandboxName(t *testing.T) {
+	// A handle of this length gives a name of exactly maxNameLength.
+	longest := maxNameLength - len("sandbox-")
+	base := strings.Repeat("a", longest)
+
+	tests := []struct {
+		handle string
+		want   string
+	}{
+		{"demo", "sandbox-demo"},
+		{base, "sandbox-" + base},
+	}
+	for _, tt := range tests {
+		if got := sandboxName(tt.handle); got != tt.want {
+			t.Errorf("sandboxName(%q) = %q, want %q", tt.handle, got, tt.want)
+		}
+	}
+
+	handles := []string{
+		base,
+		base + "b",
+		base + "c",
+		base[:longest-1] + "-b",
+		strings.Repeat("a", maxHandleLength),
+		strings.Repeat("a", maxHandleLength-1) + "b",
+		strings.Repeat("ab-", 50) + "c",
+	}
+	seen := map[string]string{}
+	for _, h := range handles {
+		name := sandboxName(h)
+		if len(name) > maxNameLength {
+			t.Errorf("sandboxName(%q) = %q is %d characters long", h, name, len(name))
+		}
+		if strings.HasSuffix(name, "-") || strings.Contains(name, "--") {
+			t.Errorf("sandboxName(%q) = %q is not a clean DNS label", h, name)
+		}
+		if name != sandboxName(h) {
+			t.Errorf("sandboxName(%q) is not stable", h)
+		}
+		if other, ok := seen[name]; ok {
+			t.Errorf("handles %q and %q both map to %q", other, h, name)
+		}
+		seen[name] = h
+	}
+}
+
+// A handle short enough to be used verbatim can be crafted to match the
+// hashed name of a long one; getClaim tells the two apart by the recorded
+// handle.
+func TestGetClaimRejectsNameCollision(t *testing.T) {
+	long := strings.Repeat("a", 60)
+	short := strings.TrimPrefix(sandboxName(long), "sandbox-")
+	if sandboxName(short) != sandboxName(long) {
+		t.Fatalf("expected %q and %q to collide", short, long)
+	}
+
+	client := kube.NewClientFrom(newFakeDynamic(testClaim(long, "", false)), fake.NewSimpleClientset())
+	if _, err := getClaim(context.Background(), client, testNamespace, long); err != nil {
+		t.Fatalf("getClaim(%q): %v", long, err)
+	}
+	if _, err := getClaim(context.Background(), client, testNamespace, short); err == nil {
+		t.Fatalf("getClaim(%q) returned the claim of %q", short, long)
+	}
+}
+
+func readyClaim(handle, pod string) *unstructured.Unstructured {
+	claim := testClaim(handle, pod, false)
+	claim.Object["status"].(map[string]interface{})["conditions"] = []interface{}{
+		map[string]interface{}{"type": "Ready", "status": "True"},
+	}
+	return claim
+}
+
+func readyPod(name string, uid types.UID) *corev1.Pod {
+	pod := testPod(name, "", "", "", "")
+	pod.UID = uid
+	pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+	return pod
+}
+
+// A resumed or restarted claim still reports Ready from before, so
+// waitForNewPod must not return until a different pod is up.
+func TestWaitForNewPod(t *testing.T) {
+	old := podRef{name: "sandbox-demo", uid: "old-uid"}
+	tests := []struct {
+		name    string
+		pod     *corev1.Pod
+		want    string
+		wantErr string
+	}{
+		{"new pod ready", readyPod("sandbox-demo", "new-uid"), "sandbox-demo", ""},
+		{"old pod still bound", readyPod("sandbox-demo", "old-uid"), "", "old pod sandbox-demo"},
+		{"no pod", nil, "", "no pod yet"},
+		{"new pod not ready", testPod("sandbox-demo", "", "", "", ""), "", "not ready"},
+	}
+	for _, tt := range tests {
+		clientset := fake.NewSimpleClientset()
+		if tt.pod != nil {
+			clientset = fake.NewSimpleClientset(tt.pod)
+		}
+		client := kube.NewClientFrom(newFakeDynamic(readyClaim("demo", "sandbox-demo")), clientset)
+
+		got, err := waitForNewPod(context.Background(), client, testNamespace, "demo", old, 50*time.Millisecond)
+		if tt.wantErr == "" {
+			if err != nil || got != tt.want {
+				t.Errorf("%s: waitForNewPod = %q, %v, want %q", tt.name, got, err, tt.want)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: waitForNewPod error = %v, want it to mention %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
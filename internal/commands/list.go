@@ -4,22 +4,38 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"text/tabwriter"
+	"os/signal"
 	"time"
 
 	"github.com/rathi/agentikube/internal/kube"
+	"github.com/rathi/agentikube/internal/output"
 	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func NewListCmd() *cobra.Command {
+	var watch bool
+	var selector string
+	var labelColumns []string
+
 	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all sandboxes",
-		Long:  "Lists all SandboxClaims in the configured namespace.",
+		Long: "Lists all SandboxClaims in the configured namespace, optionally filtered by\n" +
+			"--selector. --label-columns adds a column for each given label key. With\n" +
+			"--watch the list stays open and is redrawn as sandboxes change; when stdout\n" +
+			"is not a terminal each change is printed as one line instead.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := context.Background()
 
+			format, err := outputFormat(cmd)
+			if err != nil {
+				return err
+			}
+			if watch && format != output.Table && format != output.Wide {
+				return fmt.Errorf("--watch does not support -o %s", format)
+			}
+
 			cfg, err := loadConfig(cmd)
 			if err != nil {
 				return err
@@ -30,37 +46,49 @@ func NewListCmd() *cobra.Command {
 				return fmt.Errorf("connecting to cluster: %w", err)
 			}
 
-			list, err := client.Dynamic().Resource(sandboxClaimGVR).Namespace(cfg.Namespace).List(ctx, metav1.ListOptions{})
+			if watch {
+				ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+				defer stop()
+				return watchSandboxes(ctx, client, cfg.Namespace, selector, format, labelColumns)
+			}
+
+			list, err := client.Dynamic().Resource(sandboxClaimGVR).Namespace(cfg.Namespace).List(ctx, metav1.ListOptions{
+				LabelSelector: selector,
+			})
 			if err != nil {
 				return fmt.Errorf("listing SandboxClaims: %w", err)
 			}
 
-			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
-			fmt.Fprintln(w, "HANDLE\tSTATUS\tAGE\tPOD")
-
-			for _, item := range list.Items {
-				name := item.GetName()
-				handle := name
-				if len(name) > 8 && name[:8] == "sandbox-" {
-					handle = name[8:]
+			var p *placement
+			if format != output.Table && format != output.Name {
+				if p, err = lookupPlacement(ctx, client, cfg.Namespace); err != nil {
+					return err
 				}
-
-				status := extractStatus(item.Object)
-				podName := extractPodName(item.Object)
-				age := formatAge(item.GetCreationTimestamp().Time)
-
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", handle, status, age, podName)
 			}
-
-			w.Flush()
-			return nil
+			items := make([]output.Sandbox, 0, len(list.Items))
+			for i := range list.Items {
+				items = append(items, sandboxResult(&list.Items[i], p))
+			}
+			return printSandboxes(os.Stdout, format, items, labelColumns)
 		},
 	}
 
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "keep watching for changes")
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "only list sandboxes matching this label selector")
+	cmd.Flags().StringSliceVarP(&labelColumns, "label-columns", "L", nil, "label keys to show as extra columns")
+
 	return cmd
 }
 
 func extractStatus(obj map[string]interface{}) string {
+	// A paused sandbox keeps stale conditions from before it was scaled down.
+	if isPaused(obj) {
+		if claimAnnotation(obj, actionAnnotation) == actionHibernate {
+			return "Hibernated"
+		}
+		return "Paused"
+	}
+
 	status, ok := obj["status"].(map[string]interface{})
 	if !ok {
 		return "Unknown"
@@ -116,6 +144,40 @@ func extractPodName(obj map[string]interface{}) string {
 	return "-"
 }
 
+func isPaused(obj map[string]interface{}) bool {
+	return claimAnnotation(obj, pausedAnnotation) != ""
+}
+
+func claimAnnotation(obj map[string]interface{}, key string) string {
+	metadata, ok := obj["metadata"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	annotations, ok := metadata["annotations"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	value, _ := annotations[key].(string)
+	return value
+}
+
+// extractSandboxName returns the name of the Sandbox backing a claim. Claims
+// served from the warm pool adopt a Sandbox with a generated name.
+func extractSandboxName(obj map[string]interface{}) string {
+	status, ok := obj["status"].(map[string]interface{})
+	if ok {
+		if sandbox, ok := status["sandbox"].(map[string]interface{}); ok {
+			if name, ok := sandbox["name"].(string); ok && name != "" {
+				return name
+			}
+		}
+	}
+
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	return name
+}
+
 func formatAge(created time.Time) string {
 	d := time.Since(created)
 	switch {
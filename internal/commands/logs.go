@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/rathi/agentikube/internal/kube"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+func NewLogsCmd() *cobra.Command {
+	var follow bool
+	var previous bool
+	var timestamps bool
+	var since time.Duration
+	var tail int64
+	var waitTimeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "logs <handle>",
+		Short: "Print the logs of a sandbox",
+		Long: "Streams container logs from the sandbox pod for the given handle. If the\n" +
+			"sandbox does not have a pod yet, waits for one to be assigned.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			handle := args[0]
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			client, err := kube.NewClient()
+			if err != nil {
+				return fmt.Errorf("connecting to cluster: %w", err)
+			}
+
+			ns := cfg.Namespace
+
+			podName, err := waitForPod(ctx, client, ns, handle, waitTimeout)
+			if err != nil {
+				return err
+			}
+
+			opts := &corev1.PodLogOptions{
+				Follow:     follow,
+				Previous:   previous,
+				Timestamps: timestamps,
+			}
+			if since > 0 {
+				secs := int64(since.Seconds())
+				opts.SinceSeconds = &secs
+			}
+			if tail >= 0 {
+				opts.TailLines = &tail
+			}
+
+			// A freshly bound pod rejects log requests until its container
+			// starts, so keep retrying until the wait timeout.
+			deadline := time.Now().Add(waitTimeout)
+			for {
+				stream, err := client.Clientset().CoreV1().Pods(ns).GetLogs(podName, opts).Stream(ctx)
+				if err == nil {
+					defer stream.Close()
+					if _, err := io.Copy(os.Stdout, stream); err != nil && ctx.Err() == nil {
+						return fmt.Errorf("reading logs from pod %s: %w", podName, err)
+					}
+					return nil
+				}
+				if ctx.Err() != nil {
+					return nil
+				}
+				if previous || !errors.IsBadRequest(err) || time.Now().After(deadline) {
+					return fmt.Errorf("getting logs from pod %s: %w", podName, err)
+				}
+				if !sleepCtx(ctx, 2*time.Second) {
+					return nil
+				}
+			}
+		},
+	}
+
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "stream new log lines as they are written")
+	cmd.Flags().BoolVarP(&previous, "previous", "p", false, "print logs from the previous container instance")
+	cmd.Flags().BoolVar(&timestamps, "timestamps", false, "prefix each line with its timestamp")
+	cmd.Flags().DurationVar(&since, "since", 0, "only show logs newer than a relative duration like 5m or 1h")
+	cmd.Flags().Int64Var(&tail, "tail", -1, "number of recent lines to show (-1 for all)")
+	cmd.Flags().DurationVar(&waitTimeout, "wait-timeout", 5*time.Minute, "how long to wait for the sandbox pod")
+
+	return cmd
+}
@@ -0,0 +1,166 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/rathi/agentikube/internal/kube"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// sandboxMetadata is the user-supplied labels and annotations of a sandbox.
+// They are set on the Secret and SandboxClaim at creation and copied onto
+// the pod whenever it is (re)bound.
+type sandboxMetadata struct {
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// stateAnnotations are the annotations agentikube uses to track sandbox
+// state. They stay on the claim and are never treated as user metadata.
+var stateAnnotations = map[string]bool{
+	pausedAnnotation:       true,
+	actionAnnotation:       true,
+	actionReasonAnnotation: true,
+	actionTimeAnnotation:   true,
+	activityAnnotation:     true,
+	cpuSampleAnnotation:    true,
+	readyAfterAnnotation:   true,
+}
+
+// parseMetadata builds sandboxMetadata from repeated key=value --label and
+// --annotation flags and an optional description.
+func parseMetadata(labels, annotations []string, description string) (sandboxMetadata, error) {
+	meta := sandboxMetadata{
+		Labels:      map[string]string{},
+		Annotations: map[string]string{},
+	}
+
+	for _, kv := range labels {
+		k, v, err := splitKeyValue("label", kv)
+		if err != nil {
+			return meta, err
+		}
+		if errs := validation.IsValidLabelValue(v); len(errs) > 0 {
+			return meta, fmt.Errorf("invalid label value %q: %s", v, strings.Join(errs, "; "))
+		}
+		if k == handleLabel {
+			return meta, fmt.Errorf("label %q is managed by agentikube", k)
+		}
+		meta.Labels[k] = v
+	}
+
+	for _, kv := range annotations {
+		k, v, err := splitKeyValue("annotation", kv)
+		if err != nil {
+			return meta, err
+		}
+		if stateAnnotations[k] || k == handleAnnotation {
+			return meta, fmt.Errorf("annotation %q is managed by agentikube", k)
+		}
+		meta.Annotations[k] = v
+	}
+
+	if description != "" {
+		meta.Annotations[descriptionAnnotation] = description
+	}
+	return meta, nil
+}
+
+func splitKeyValue(kind, kv string) (string, string, error) {
+	k, v, ok := strings.Cut(kv, "=")
+	if !ok {
+		return "", "", fmt.Errorf("invalid %s %q: expected key=value", kind, kv)
+	}
+	if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+		return "", "", fmt.Errorf("invalid %s key %q: %s", kind, k, strings.Join(errs, "; "))
+	}
+	return k, v, nil
+}
+
+// withHandle returns a copy of m that also records handle, as a label when
+// it is short enough to be a label value.
+func (m sandboxMetadata) withHandle(handle string) sandboxMetadata {
+	out := sandboxMetadata{
+		Labels:      make(map[string]string, len(m.Labels)+1),
+		Annotations: make(map[string]string, len(m.Annotations)+1),
+	}
+	for k, v := range m.Labels {
+		out.Labels[k] = v
+	}
+	for k, v := range m.Annotations {
+		out.Annotations[k] = v
+	}
+
+	out.Annotations[handleAnnotation] = handle
+	if len(validation.IsValidLabelValue(handle)) == 0 {
+		out.Labels[handleLabel] = handle
+	} else {
+		delete(out.Labels, handleLabel)
+	}
+	return out
+}
+
+// claimMetadata returns the user metadata recorded on a SandboxClaim.
+func claimMetadata(claim *unstructured.Unstructured) sandboxMetadata {
+	meta := sandboxMetadata{
+		Labels:      claim.GetLabels(),
+		Annotations: map[string]string{},
+	}
+	for k, v := range claim.GetAnnotations() {
+		if stateAnnotations[k] || k == "kubectl.kubernetes.io/last-applied-configuration" {
+			continue
+		}
+		meta.Annotations[k] = v
+	}
+	return meta
+}
+
+// syncPodMetadata copies the claim's user labels and annotations onto the
+// pod it is bound to. Pods come from the warm pool or are recreated on
+// resume, so this runs whenever a sandbox becomes ready.
+func syncPodMetadata(ctx context.Context, client *kube.Client, namespace, handle string) error {
+	claim, err := getClaim(ctx, client, namespace, handle)
+	if err != nil {
+		return err
+	}
+	meta := claimMetadata(claim)
+	if len(meta.Labels) == 0 && len(meta.Annotations) == 0 {
+		return nil
+	}
+
+	podName := extractPodName(claim.Object)
+	if podName == "-" || isPaused(claim.Object) {
+		return nil
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels":      meta.Labels,
+			"annotations": meta.Annotations,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Clientset().CoreV1().Pods(namespace).Patch(ctx, podName, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("labeling pod %s: %w", podName, err)
+	}
+	return nil
+}
+
+// stringMap converts a string map for use in unstructured objects.
+func stringMap(m map[string]string) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,211 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rathi/agentikube/internal/kube"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func NewPauseCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pause <handle>",
+		Short: "Pause a sandbox, keeping its workspace",
+		Long: "Scales the sandbox to zero so its pod and node capacity are released. The\n" +
+			"Secret, workspace PVC and SandboxClaim are kept; use resume to bring it back.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			handle := args[0]
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			client, err := kube.NewClient()
+			if err != nil {
+				return fmt.Errorf("connecting to cluster: %w", err)
+			}
+
+			paused, err := pauseSandbox(ctx, client, cfg.Namespace, handle, actionPause, "paused by user")
+			if err != nil {
+				return err
+			}
+			if !paused {
+				fmt.Printf("sandbox %q is already paused\n", handle)
+				return nil
+			}
+
+			fmt.Printf("[ok] sandbox %q paused\n", handle)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func NewResumeCmd() *cobra.Command {
+	var timeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "resume <handle>",
+		Short: "Resume a paused sandbox",
+		Long: "Scales a paused sandbox back to one pod on its existing workspace PVC and waits\n" +
+			"for it to become ready. Warm pool pods carry their own workspace, so a resumed\n" +
+			"sandbox always starts a fresh pod.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			handle := args[0]
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			client, err := kube.NewClient()
+			if err != nil {
+				return fmt.Errorf("connecting to cluster: %w", err)
+			}
+
+			ns := cfg.Namespace
+
+			old, resumed, err := resumeSandbox(ctx, client, ns, handle, actionResume, "resumed by user")
+			if err != nil {
+				return err
+			}
+			if !resumed {
+				fmt.Printf("sandbox %q is not paused\n", handle)
+				return nil
+			}
+			fmt.Printf("[ok] sandbox %q resumed\n", handle)
+
+			fmt.Println("waiting for sandbox to be ready...")
+			if _, err := waitForNewPod(ctx, client, ns, handle, old, timeout); err != nil {
+				return err
+			}
+			if err := syncPodMetadata(ctx, client, ns, handle); err != nil {
+				fmt.Printf("[warn] %v\n", err)
+			}
+
+			fmt.Printf("\nsandbox %q is ready\n", handle)
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&timeout, "timeout", defaultReadyTimeout, "how long to wait for the sandbox to become ready")
+
+	return cmd
+}
+
+// pauseSandbox scales the Sandbox behind handle to zero replicas and marks
+// its claim as paused, recording action and reason. It reports false if the
+// sandbox was already paused.
+func pauseSandbox(ctx context.Context, client *kube.Client, namespace, handle, action, reason string) (bool, error) {
+	claim, err := getClaim(ctx, client, namespace, handle)
+	if err != nil {
+		return false, err
+	}
+	if isPaused(claim.Object) {
+		return false, nil
+	}
+
+	// Mark the claim first so nothing treats a scaled-down sandbox as running,
+	// and put the previous annotations back if the scale fails.
+	patch := actionAnnotations(action, reason)
+	patch[pausedAnnotation] = patch[actionTimeAnnotation]
+	if err := annotateClaim(ctx, client, namespace, claim.GetName(), patch); err != nil {
+		return false, err
+	}
+
+	if err := scaleSandbox(ctx, client, namespace, extractSandboxName(claim.Object), 0); err != nil {
+		restore := map[string]interface{}{pausedAnnotation: nil}
+		for _, key := range []string{actionAnnotation, actionReasonAnnotation, actionTimeAnnotation} {
+			if v := claimAnnotation(claim.Object, key); v != "" {
+				restore[key] = v
+			} else {
+				restore[key] = nil
+			}
+		}
+		if rerr := annotateClaim(ctx, client, namespace, claim.GetName(), restore); rerr != nil {
+			return false, fmt.Errorf("%w (and restoring annotations failed: %v)", err, rerr)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// resumeSandbox scales the Sandbox behind handle back to one replica and
+// clears the paused marker, recording action and reason. It returns the pod
+// the claim was bound to before scaling up, for waitForNewPod, and reports
+// false if the sandbox was not paused.
+func resumeSandbox(ctx context.Context, client *kube.Client, namespace, handle, action, reason string) (podRef, bool, error) {
+	claim, err := getClaim(ctx, client, namespace, handle)
+	if err != nil {
+		return podRef{}, false, err
+	}
+	if !isPaused(claim.Object) {
+		return podRef{}, false, nil
+	}
+
+	old, err := currentPod(ctx, client, namespace, claim)
+	if err != nil {
+		return podRef{}, false, err
+	}
+	if err := scaleSandbox(ctx, client, namespace, extractSandboxName(claim.Object), 1); err != nil {
+		return podRef{}, false, err
+	}
+
+	patch := actionAnnotations(action, reason)
+	patch[pausedAnnotation] = nil
+	if err := annotateClaim(ctx, client, namespace, claim.GetName(), patch); err != nil {
+		return podRef{}, false, err
+	}
+	return old, true, nil
+}
+
+func actionAnnotations(action, reason string) map[string]interface{} {
+	return map[string]interface{}{
+		actionAnnotation:       action,
+		actionReasonAnnotation: reason,
+		actionTimeAnnotation:   time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+func scaleSandbox(ctx context.Context, client *kube.Client, namespace, name string, replicas int) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{"replicas": replicas},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Dynamic().Resource(sandboxGVR).Namespace(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("scaling Sandbox %q to %d: %w", name, replicas, err)
+	}
+	return nil
+}
+
+// annotateClaim merge-patches annotations onto a SandboxClaim. A nil value
+// removes the annotation.
+func annotateClaim(ctx context.Context, client *kube.Client, namespace, name string, annotations map[string]interface{}) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": annotations},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Dynamic().Resource(sandboxClaimGVR).Namespace(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("annotating SandboxClaim %q: %w", name, err)
+	}
+	return nil
+}
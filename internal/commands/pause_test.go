@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rathi/agentikube/internal/kube"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// The claim is marked paused before scaling down; if the scale fails the
+// marker must not be left behind on a running sandbox.
+func TestPauseSandboxRestoresAnnotationsWhenScaleFails(t *testing.T) {
+	claim := testClaim("demo", "sandbox-demo", false)
+	claim.SetAnnotations(map[string]string{
+		handleAnnotation:       "demo",
+		actionAnnotation:       actionWake,
+		actionReasonAnnotation: "woken by exec",
+	})
+	dyn := newFakeDynamic(claim)
+	client := kube.NewClientFrom(dyn, fake.NewSimpleClientset())
+
+	// There is no Sandbox object, so scaling it fails.
+	if _, err := pauseSandbox(context.Background(), client, testNamespace, "demo", actionPause, "paused by user"); err == nil {
+		t.Fatal("pauseSandbox succeeded without a Sandbox to scale")
+	}
+
+	got, err := dyn.Resource(sandboxClaimGVR).Namespace(testNamespace).Get(context.Background(), claim.GetName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isPaused(got.Object) {
+		t.Error("claim is still marked paused after the scale failed")
+	}
+	if a := got.GetAnnotations(); a[actionAnnotation] != actionWake || a[actionReasonAnnotation] != "woken by exec" {
+		t.Errorf("annotations = %v, want the previous action restored", a)
+	}
+	if _, ok := got.GetAnnotations()[actionTimeAnnotation]; ok {
+		t.Error("action time annotation was added and not removed")
+	}
+}
@@ -0,0 +1,169 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rathi/agentikube/internal/kube"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// phaseInterval is how often the phase tracker polls the claim, pod and
+// events while a sandbox starts.
+const phaseInterval = 2 * time.Second
+
+// phaseTracker reports the startup phases of a sandbox: whether the claim
+// was served from the warm pool, node provisioning, volume attach, image
+// pull and startup probe attempts. Each phase is printed once with the time
+// elapsed since start.
+type phaseTracker struct {
+	client    *kube.Client
+	out       io.Writer
+	namespace string
+	handle    string
+	start     time.Time
+
+	sandbox      string
+	pod          string
+	seenEvents   map[types.UID]int32
+	probeFailure int32
+}
+
+func newPhaseTracker(client *kube.Client, out io.Writer, namespace, handle string) *phaseTracker {
+	return &phaseTracker{
+		client:     client,
+		out:        out,
+		namespace:  namespace,
+		handle:     handle,
+		start:      time.Now(),
+		seenEvents: map[types.UID]int32{},
+	}
+}
+
+// run polls until ctx is done. Lookup failures are ignored: progress output
+// must never fail the wait it decorates.
+func (t *phaseTracker) run(ctx context.Context) {
+	for {
+		t.poll(ctx)
+		if !sleepCtx(ctx, phaseInterval) {
+			return
+		}
+	}
+}
+
+func (t *phaseTracker) poll(ctx context.Context) {
+	claim, err := getClaim(ctx, t.client, t.namespace, t.handle)
+	if err != nil {
+		return
+	}
+
+	if sandbox := extractSandboxName(claim.Object); t.sandbox == "" && sandbox != claim.GetName() {
+		t.sandbox = sandbox
+		t.report("warm pool hit: adopted sandbox %s", sandbox)
+	}
+
+	podName := extractPodName(claim.Object)
+	if podName == "-" {
+		// A sandbox started for this claim names its pod after itself.
+		podName = extractSandboxName(claim.Object)
+	}
+	pod, err := t.client.Clientset().CoreV1().Pods(t.namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return
+	}
+	if t.pod == "" {
+		t.pod = pod.Name
+		if t.sandbox == "" && pod.CreationTimestamp.Before(&metav1.Time{Time: claim.GetCreationTimestamp().Time}) {
+			t.sandbox = pod.Name
+			t.report("warm pool hit: adopted pod %s", pod.Name)
+		} else if t.sandbox == "" {
+			t.sandbox = pod.Name
+			t.report("warm pool miss: starting a new pod %s", pod.Name)
+		}
+	}
+
+	events, err := t.client.Clientset().CoreV1().Events(t.namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.AndSelectors(
+			fields.OneTermEqualSelector("involvedObject.kind", "Pod"),
+			fields.OneTermEqualSelector("involvedObject.name", pod.Name),
+		).String(),
+	})
+	if err != nil {
+		return
+	}
+	sort.Slice(events.Items, func(i, j int) bool {
+		return eventTime(&events.Items[i]).Before(eventTime(&events.Items[j]))
+	})
+	// Events from before the claim belong to a warm pool pod's own startup.
+	created := claim.GetCreationTimestamp().Time
+	for i := range events.Items {
+		ev := &events.Items[i]
+		if eventTime(ev).Before(created) {
+			continue
+		}
+		seen, ok := t.seenEvents[ev.UID]
+		t.seenEvents[ev.UID] = ev.Count
+		switch {
+		case !ok:
+			t.reportEvent(ev, ev.Count)
+		case ev.Reason == "Unhealthy" && ev.Count > seen:
+			// Repeated probe failures update the count of one event.
+			t.reportEvent(ev, ev.Count-seen)
+		}
+	}
+}
+
+// reportEvent turns a pod event into a phase line. Events that say nothing
+// about startup progress are skipped. n is the number of new occurrences.
+func (t *phaseTracker) reportEvent(ev *corev1.Event, n int32) {
+	msg := strings.TrimSpace(ev.Message)
+	switch ev.Reason {
+	case "FailedScheduling":
+		t.report("waiting for a node: %s", msg)
+	case "Nominated":
+		t.report("Karpenter is provisioning a node: %s", msg)
+	case "Scheduled":
+		t.report("scheduled: %s", msg)
+	case "SuccessfulAttachVolume":
+		t.report("volume attached: %s", msg)
+	case "FailedAttachVolume", "FailedMount":
+		t.report("[warn] volume not ready yet: %s", msg)
+	case "Pulling":
+		t.report("pulling image: %s", msg)
+	case "Pulled":
+		t.report("image pulled: %s", msg)
+	case "Failed", "BackOff", "ErrImagePull":
+		t.report("[warn] %s", msg)
+	case "Started":
+		t.report("container started")
+	case "Unhealthy":
+		if strings.HasPrefix(msg, "Startup probe") {
+			t.probeFailure += max(n, 1)
+			t.report("startup probe attempt %d failed, still starting", t.probeFailure)
+		}
+	}
+}
+
+func (t *phaseTracker) report(format string, args ...interface{}) {
+	elapsed := time.Since(t.start).Round(time.Second)
+	fmt.Fprintf(t.out, "  [%5s] %s\n", elapsed, fmt.Sprintf(format, args...))
+}
+
+// eventTime returns the most precise timestamp an event carries.
+func eventTime(ev *corev1.Event) time.Time {
+	switch {
+	case !ev.EventTime.IsZero():
+		return ev.EventTime.Time
+	case !ev.LastTimestamp.IsZero():
+		return ev.LastTimestamp.Time
+	default:
+		return ev.FirstTimestamp.Time
+	}
+}
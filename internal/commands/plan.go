@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rathi/agentikube/internal/config"
+	"github.com/rathi/agentikube/internal/kube"
+	"github.com/rathi/agentikube/internal/manifest"
+	"github.com/spf13/cobra"
+)
+
+// planChangesExitCode is the exit status of `plan` and `up --diff` when
+// applying would change the cluster. Errors exit with 1.
+const planChangesExitCode = 2
+
+func NewPlanCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Show what `up` would change in the cluster",
+		Long: "Runs a server-side dry-run apply of every manifest `up` would apply and\n" +
+			"compares the result with the live objects, field by field. Managed fields,\n" +
+			"server-populated metadata and status are ignored.\n\n" +
+			"Exits with 0 when the cluster is up to date and with 2 when there are changes,\n" +
+			"so it can gate config changes in CI. Same as `up --diff`.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			return runPlan(context.Background(), cfg)
+		},
+	}
+
+	return cmd
+}
+
+// runPlan prints the plan for cfg and returns an *kube.ExitError when there
+// are changes.
+func runPlan(ctx context.Context, cfg *config.Config) error {
+	manifests, err := manifest.Generate(cfg)
+	if err != nil {
+		return fmt.Errorf("generating manifests: %w", err)
+	}
+
+	client, err := kube.NewClient()
+	if err != nil {
+		return fmt.Errorf("connecting to cluster: %w", err)
+	}
+
+	items, err := client.Plan(ctx, manifests)
+	if err != nil {
+		return fmt.Errorf("planning: %w", err)
+	}
+
+	if printPlan(os.Stdout, items) {
+		return &kube.ExitError{Code: planChangesExitCode}
+	}
+	return nil
+}
+
+// printPlan prints every object with its action and changed fields, then a
+// summary. It reports whether anything would change.
+func printPlan(w io.Writer, items []kube.PlanItem) bool {
+	counts := map[string]int{}
+	for _, item := range items {
+		counts[item.Action]++
+
+		name := item.Name
+		if item.Namespace != "" {
+			name = item.Namespace + "/" + name
+		}
+		switch item.Action {
+		case kube.ActionCreate:
+			fmt.Fprintf(w, "+ %s %s (create)\n", item.Kind, name)
+		case kube.ActionUpdate:
+			fmt.Fprintf(w, "~ %s %s (update)\n", item.Kind, name)
+		default:
+			fmt.Fprintf(w, "  %s %s (unchanged)\n", item.Kind, name)
+			continue
+		}
+
+		for _, c := range item.Changes {
+			switch {
+			case c.Old == nil:
+				fmt.Fprintf(w, "    + %s: %s\n", c.Path, kube.FormatValue(c.New))
+			case c.New == nil:
+				fmt.Fprintf(w, "    - %s: %s\n", c.Path, kube.FormatValue(c.Old))
+			default:
+				fmt.Fprintf(w, "    ~ %s: %s -> %s\n", c.Path, kube.FormatValue(c.Old), kube.FormatValue(c.New))
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "\nplan: %d to create, %d to update, %d unchanged\n",
+		counts[kube.ActionCreate], counts[kube.ActionUpdate], counts[kube.ActionUnchanged])
+	return counts[kube.ActionCreate]+counts[kube.ActionUpdate] > 0
+}
@@ -0,0 +1,164 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rathi/agentikube/internal/kube"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	portForwardRetryDelay = 2 * time.Second
+	podCheckInterval      = 5 * time.Second
+)
+
+func NewPortForwardCmd() *cobra.Command {
+	var ports []string
+	var addresses []string
+
+	cmd := &cobra.Command{
+		Use:   "port-forward <handle>",
+		Short: "Forward local ports to a sandbox",
+		Long: "Forwards local ports to the sandbox pod for the given handle. By default all\n" +
+			"ports from sandbox.ports are forwarded to the same local port. The forward is\n" +
+			"re-established automatically when the sandbox pod is replaced.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			handle := args[0]
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			if len(ports) == 0 {
+				for _, p := range cfg.Sandbox.Ports {
+					ports = append(ports, strconv.Itoa(p))
+				}
+			}
+			for _, p := range ports {
+				if err := validatePortSpec(p); err != nil {
+					return err
+				}
+			}
+
+			client, err := kube.NewClient()
+			if err != nil {
+				return fmt.Errorf("connecting to cluster: %w", err)
+			}
+
+			ns := cfg.Namespace
+			connected := false
+			keepActive(ctx, client, ns, handle)
+
+			for {
+				podName, err := resolvePod(ctx, client, ns, handle)
+				if err != nil {
+					if !errors.Is(err, errNoPod) {
+						return err
+					}
+					fmt.Printf("waiting for sandbox %q to get a pod...\n", handle)
+					if !sleepCtx(ctx, portForwardRetryDelay) {
+						return nil
+					}
+					continue
+				}
+
+				fwdCtx, cancel := context.WithCancel(ctx)
+				go watchPodChange(fwdCtx, cancel, client, ns, handle, podName)
+
+				ready := make(chan struct{})
+				fmt.Printf("forwarding to pod %s...\n", podName)
+				err = client.PortForward(fwdCtx, kube.PortForwardOptions{
+					Namespace: ns,
+					Pod:       podName,
+					Addresses: addresses,
+					Ports:     ports,
+					Ready:     ready,
+					Out:       os.Stdout,
+					ErrOut:    os.Stderr,
+				})
+				cancel()
+
+				select {
+				case <-ready:
+					connected = true
+				default:
+				}
+
+				if ctx.Err() != nil {
+					return nil
+				}
+				if err != nil {
+					// Failing before the first successful forward usually means
+					// a local problem such as a port already in use.
+					if !connected {
+						return err
+					}
+					fmt.Printf("[warn] %v\n", err)
+				}
+
+				fmt.Println("reconnecting...")
+				if !sleepCtx(ctx, portForwardRetryDelay) {
+					return nil
+				}
+			}
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&ports, "port", "p", nil, "port to forward as local:remote or port (repeatable, default: all sandbox.ports)")
+	cmd.Flags().StringSliceVar(&addresses, "address", []string{"localhost"}, "local addresses to listen on")
+
+	return cmd
+}
+
+// watchPodChange polls the SandboxClaim and calls cancel once it is no longer
+// bound to podName, so the caller can reconnect to the replacement pod, or
+// once the claim is gone.
+func watchPodChange(ctx context.Context, cancel context.CancelFunc, client *kube.Client, namespace, handle, podName string) {
+	for sleepCtx(ctx, podCheckInterval) {
+		current, err := resolvePod(ctx, client, namespace, handle)
+		if apierrors.IsNotFound(err) {
+			// The sandbox was destroyed; the caller reports it when it
+			// looks the pod up again.
+			cancel()
+			return
+		}
+		if err != nil && !errors.Is(err, errNoPod) {
+			continue
+		}
+		if current != podName {
+			fmt.Printf("sandbox pod %s was replaced\n", podName)
+			cancel()
+			return
+		}
+	}
+}
+
+// validatePortSpec checks a port-forward spec of the form "port" or
+// "local:remote". An empty local port picks a random free port.
+func validatePortSpec(spec string) error {
+	local, remote, found := strings.Cut(spec, ":")
+	if !found {
+		remote = local
+	}
+	if local != "" {
+		if _, err := strconv.ParseUint(local, 10, 16); err != nil {
+			return fmt.Errorf("invalid local port in %q", spec)
+		}
+	}
+	if n, err := strconv.ParseUint(remote, 10, 16); err != nil || n == 0 {
+		return fmt.Errorf("invalid remote port in %q", spec)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// progress is an io.Writer that counts bytes passing through it and redraws
+// a single status line on stderr. It is silent when stderr is not a terminal.
+type progress struct {
+	mu      sync.Mutex
+	label   string
+	total   int64
+	written int64
+	last    time.Time
+	enabled bool
+}
+
+func newProgress(label string, total int64) *progress {
+	return &progress{
+		label:   label,
+		total:   total,
+		enabled: term.IsTerminal(int(os.Stderr.Fd())),
+	}
+}
+
+func (p *progress) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.written += int64(len(b))
+	if p.enabled && time.Since(p.last) >= 200*time.Millisecond {
+		p.draw()
+		p.last = time.Now()
+	}
+	return len(b), nil
+}
+
+// Done draws the final state and ends the status line.
+func (p *progress) Done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.enabled {
+		p.draw()
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+func (p *progress) draw() {
+	if p.total > 0 {
+		// Archive headers make the stream slightly larger than the content.
+		pct := min(p.written*100/p.total, 100)
+		fmt.Fprintf(os.Stderr, "\r%s %s / %s (%d%%)   ", p.label, formatBytes(p.written), formatBytes(p.total), pct)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\r%s %s   ", p.label, formatBytes(p.written))
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
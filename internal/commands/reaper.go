@@ -0,0 +1,328 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rathi/agentikube/internal/config"
+	"github.com/rathi/agentikube/internal/kube"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// idleProbeScript prints the container's cgroup CPU usage followed by its
+// TCP socket tables. cgroup v2 reports usage_usec; v1 is normalised to a
+// usage_nsec line.
+const idleProbeScript = `cat /sys/fs/cgroup/cpu.stat 2>/dev/null || echo "usage_nsec $(cat /sys/fs/cgroup/cpuacct/cpuacct.usage 2>/dev/null)"
+cat /proc/net/tcp /proc/net/tcp6 2>/dev/null`
+
+func NewReaperCmd() *cobra.Command {
+	var once bool
+	var dryRun bool
+	var interval time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "reaper",
+		Short: "Hibernate idle sandboxes",
+		Long: "Periodically checks every running sandbox and hibernates the idle ones by\n" +
+			"scaling them to zero, keeping the Secret and workspace PVC. A sandbox is idle\n" +
+			"when, for sandbox.idle.windowMinutes, no ssh/exec/cp/port-forward session was\n" +
+			"open, no connections are established on the probe or ingress ports, and its\n" +
+			"average CPU stayed below sandbox.idle.cpuMillicores. Hibernated sandboxes wake\n" +
+			"on the next ssh or exec. Each decision is recorded on the SandboxClaim.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			if !cfg.Sandbox.Idle.Enabled {
+				return fmt.Errorf("idle detection is disabled (set sandbox.idle.enabled: true)")
+			}
+
+			client, err := kube.NewClient()
+			if err != nil {
+				return fmt.Errorf("connecting to cluster: %w", err)
+			}
+
+			r := &reaper{client: client, cfg: cfg, dryRun: dryRun}
+			for {
+				if err := r.sweep(ctx); err != nil {
+					if once {
+						return err
+					}
+					fmt.Printf("[warn] %v\n", err)
+				}
+				if once || !sleepCtx(ctx, interval) {
+					return nil
+				}
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&once, "once", false, "run a single check and exit (for cron jobs)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "report idle sandboxes without hibernating them")
+	cmd.Flags().DurationVar(&interval, "interval", 5*time.Minute, "time between checks")
+
+	return cmd
+}
+
+type reaper struct {
+	client *kube.Client
+	cfg    *config.Config
+	dryRun bool
+}
+
+// sweep checks every running sandbox once and hibernates the idle ones.
+func (r *reaper) sweep(ctx context.Context) error {
+	ns := r.cfg.Namespace
+
+	list, err := r.client.Dynamic().Resource(sandboxClaimGVR).Namespace(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("listing SandboxClaims: %w", err)
+	}
+
+	for i := range list.Items {
+		claim := &list.Items[i]
+		if isPaused(claim.Object) {
+			continue
+		}
+		handle := handleFromClaim(claim)
+
+		idle, reason, err := r.check(ctx, claim)
+		if err != nil {
+			fmt.Printf("%s: [warn] %v\n", handle, err)
+			continue
+		}
+		if !idle {
+			fmt.Printf("%s: active (%s)\n", handle, reason)
+			continue
+		}
+
+		if r.dryRun {
+			fmt.Printf("%s: idle (%s), would hibernate\n", handle, reason)
+			continue
+		}
+		if _, err := pauseSandbox(ctx, r.client, ns, handle, actionHibernate, reason); err != nil {
+			fmt.Printf("%s: [warn] hibernating: %v\n", handle, err)
+			continue
+		}
+		fmt.Printf("%s: [ok] hibernated (%s)\n", handle, reason)
+	}
+
+	return nil
+}
+
+// check decides whether the sandbox behind claim is idle and explains why.
+func (r *reaper) check(ctx context.Context, claim *unstructured.Unstructured) (bool, string, error) {
+	idleCfg := r.cfg.Sandbox.Idle
+	window := time.Duration(idleCfg.WindowMinutes) * time.Minute
+	now := time.Now()
+
+	// Recent sessions, or a recent wake/resume, keep the sandbox awake.
+	last := claim.GetCreationTimestamp().Time
+	for _, key := range []string{activityAnnotation, actionTimeAnnotation} {
+		if t, err := time.Parse(time.RFC3339, claimAnnotation(claim.Object, key)); err == nil && t.After(last) {
+			last = t
+		}
+	}
+	if since := now.Sub(last); since < window {
+		return false, fmt.Sprintf("last activity %s ago", since.Round(time.Second)), nil
+	}
+
+	podName := extractPodName(claim.Object)
+	if podName == "-" {
+		return false, "no pod assigned", nil
+	}
+
+	ports := r.watchedPorts()
+	var out bytes.Buffer
+	err := r.client.Exec(ctx, kube.ExecOptions{
+		Namespace: claim.GetNamespace(),
+		Pod:       podName,
+		Command:   []string{"sh", "-c", idleProbeScript},
+		Stdout:    &out,
+	})
+	if err != nil {
+		return false, "", fmt.Errorf("probing pod %s: %w", podName, err)
+	}
+
+	usage, conns, err := parseIdleProbe(out.String(), ports)
+	if err != nil {
+		return false, "", fmt.Errorf("probing pod %s: %w", podName, err)
+	}
+
+	cpu := measureCPU(usage, claimAnnotation(claim.Object, cpuSampleAnnotation), now, window)
+	if cpu.resample {
+		sample := fmt.Sprintf("%d@%d", usage, now.Unix())
+		if err := annotateClaim(ctx, r.client, claim.GetNamespace(), claim.GetName(), map[string]interface{}{
+			cpuSampleAnnotation: sample,
+		}); err != nil {
+			return false, "", err
+		}
+	}
+
+	if conns > 0 {
+		return false, fmt.Sprintf("%d open connections on ports %s", conns, joinPorts(ports)), nil
+	}
+	if !cpu.complete {
+		return false, cpu.reason, nil
+	}
+	if cpu.millicores >= int64(idleCfg.CPUMillicores) {
+		return false, fmt.Sprintf("CPU %dm >= %dm", cpu.millicores, idleCfg.CPUMillicores), nil
+	}
+
+	return true, fmt.Sprintf("idle for %s: no sessions, no connections on ports %s, CPU %dm < %dm",
+		window, joinPorts(ports), cpu.millicores, idleCfg.CPUMillicores), nil
+}
+
+// cpuWindow is a CPU usage reading compared with the sample recorded at the
+// start of the idle window.
+type cpuWindow struct {
+	// resample is set when the current reading should be recorded as the
+	// start of a new window.
+	resample bool
+	// complete is set when a whole window has elapsed; millicores is then
+	// the average usage over it. Otherwise reason says why not.
+	complete   bool
+	millicores int64
+	reason     string
+}
+
+// measureCPU compares usage, in microseconds of CPU time, with sample. The
+// window restarts when there is no usable sample, the counter went backwards
+// (the container restarted) or the sample is from the future.
+func measureCPU(usage int64, sample string, now time.Time, window time.Duration) cpuWindow {
+	prevUsage, prevAt, ok := parseCPUSample(sample)
+	elapsed := now.Sub(prevAt)
+	if !ok || usage < prevUsage || elapsed < 0 {
+		return cpuWindow{resample: true, reason: "collecting CPU samples"}
+	}
+	seconds := int64(elapsed.Seconds())
+	if elapsed < window || seconds == 0 {
+		return cpuWindow{reason: fmt.Sprintf("CPU window %s of %s", elapsed.Round(time.Second), window)}
+	}
+
+	// usec per second / 1000 is millicores.
+	return cpuWindow{
+		resample:   true,
+		complete:   true,
+		millicores: (usage - prevUsage) / seconds / 1000,
+	}
+}
+
+// watchedPorts returns the probe port and ingress ports, deduplicated.
+func (r *reaper) watchedPorts() []int {
+	seen := map[int]bool{}
+	var ports []int
+	for _, p := range append([]int{r.cfg.Sandbox.Probes.Port}, r.cfg.Sandbox.NetworkPolicy.IngressPorts...) {
+		if p > 0 && !seen[p] {
+			seen[p] = true
+			ports = append(ports, p)
+		}
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+// parseIdleProbe extracts the CPU usage in microseconds and the number of
+// established, non-loopback TCP connections on ports from idleProbeScript
+// output.
+func parseIdleProbe(out string, ports []int) (usageUsec int64, conns int, err error) {
+	watched := map[int64]bool{}
+	for _, p := range ports {
+		watched[int64(p)] = true
+	}
+
+	usageUsec = -1
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		switch fields[0] {
+		case "usage_usec":
+			usageUsec, _ = strconv.ParseInt(fields[1], 10, 64)
+			continue
+		case "usage_nsec":
+			if n, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+				usageUsec = n / 1000
+			}
+			continue
+		}
+
+		// /proc/net/tcp rows: "sl local_address rem_address st ...", with
+		// addresses as hex IP:port and st 01 meaning ESTABLISHED.
+		if len(fields) < 4 || !strings.HasSuffix(fields[0], ":") || fields[3] != "01" {
+			continue
+		}
+		_, localPort, ok := strings.Cut(fields[1], ":")
+		if !ok {
+			continue
+		}
+		port, err := strconv.ParseInt(localPort, 16, 32)
+		if err != nil || !watched[port] {
+			continue
+		}
+		remoteIP, _, _ := strings.Cut(fields[2], ":")
+		if isLoopbackHex(remoteIP) {
+			continue
+		}
+		conns++
+	}
+
+	if usageUsec < 0 {
+		return 0, 0, fmt.Errorf("could not read cgroup CPU usage")
+	}
+	return usageUsec, conns, nil
+}
+
+// isLoopbackHex reports whether a /proc/net/tcp{,6} address is loopback.
+// IPv4 addresses are little-endian, so 127.x.x.x ends in 7F.
+func isLoopbackHex(ip string) bool {
+	switch len(ip) {
+	case 8:
+		return strings.HasSuffix(ip, "7F")
+	case 32:
+		if ip == "00000000000000000000000001000000" {
+			return true
+		}
+		return strings.HasPrefix(ip, "0000000000000000FFFF0000") && strings.HasSuffix(ip, "7F")
+	}
+	return false
+}
+
+func parseCPUSample(s string) (usage int64, at time.Time, ok bool) {
+	u, ts, found := strings.Cut(s, "@")
+	if !found {
+		return 0, time.Time{}, false
+	}
+	usage, err := strconv.ParseInt(u, 10, 64)
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	return usage, time.Unix(unix, 0), true
+}
+
+func joinPorts(ports []int) string {
+	parts := make([]string, len(ports))
+	for i, p := range ports {
+		parts[i] = strconv.Itoa(p)
+	}
+	return strings.Join(parts, ",")
+}
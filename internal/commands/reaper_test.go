@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+const tcpHeader = "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode"
+
+// tcpRow formats a /proc/net/tcp{,6} row. Port 1F90 is 8080 and 0016 is 22.
+func tcpRow(local, remote, state string) string {
+	return "   0: " + local + " " + remote + " " + state + " 00000000:00000000 00:00000000 00000000  1000        0 12345 1 0000000000000000 20 4 30 10 -1"
+}
+
+func TestParseIdleProbe(t *testing.T) {
+	const (
+		established = "01"
+		listen      = "0A"
+
+		v4Any        = "00000000"
+		v4Loopback   = "0100007F"
+		v4Remote     = "0A000001" // 1.0.0.10
+		v6Any        = "00000000000000000000000000000000"
+		v6Loopback   = "00000000000000000000000001000000"
+		v6Mapped     = "0000000000000000FFFF00000100007F"
+		v6MappedNet  = "0000000000000000FFFF00000A00000A"
+		v6Remote     = "B80D0120000000000000000001000000"
+		cgroupV2Stat = "usage_usec 4200000\nuser_usec 3000000\nsystem_usec 1200000"
+	)
+	ports := []int{8080}
+
+	tests := []struct {
+		name  string
+		out   []string
+		usage int64
+		conns int
+		err   bool
+	}{
+		{
+			name:  "cgroup v2, no sockets",
+			out:   []string{cgroupV2Stat, tcpHeader},
+			usage: 4200000,
+		},
+		{
+			name:  "cgroup v1 in nanoseconds",
+			out:   []string{"usage_nsec 4200000123"},
+			usage: 4200000,
+		},
+		{
+			name: "no cgroup usage",
+			out:  []string{"usage_nsec ", tcpHeader},
+			err:  true,
+		},
+		{
+			name: "listening sockets are not connections",
+			out: []string{cgroupV2Stat, tcpHeader,
+				tcpRow(v4Any+":1F90", v4Any+":0000", listen),
+				tcpRow(v6Any+":1F90", v6Any+":0000", listen),
+			},
+			usage: 4200000,
+		},
+		{
+			name: "loopback connections are ignored",
+			out: []string{cgroupV2Stat, tcpHeader,
+				tcpRow(v4Loopback+":1F90", v4Loopback+":C350", established),
+				tcpRow(v6Loopback+":1F90", v6Loopback+":C350", established),
+				tcpRow(v6Mapped+":1F90", v6Mapped+":C350", established),
+			},
+			usage: 4200000,
+		},
+		{
+			name: "remote connections on watched ports count",
+			out: []string{cgroupV2Stat, tcpHeader,
+				tcpRow(v4Any+":1F90", v4Remote+":C350", established),
+				tcpRow(v6Any+":1F90", v6Remote+":C350", established),
+				tcpRow(v6Any+":1F90", v6MappedNet+":C351", established),
+			},
+			usage: 4200000,
+			conns: 3,
+		},
+		{
+			name: "other ports and remote ports are ignored",
+			out: []string{cgroupV2Stat, tcpHeader,
+				tcpRow(v4Any+":0016", v4Remote+":1F90", established),
+				tcpRow(v4Any+":C350", v4Remote+":1F90", established),
+			},
+			usage: 4200000,
+		},
+	}
+	for _, tt := range tests {
+		usage, conns, err := parseIdleProbe(strings.Join(tt.out, "\n")+"\n", ports)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: err = %v, want error %v", tt.name, err, tt.err)
+			continue
+		}
+		if usage != tt.usage || conns != tt.conns {
+			t.Errorf("%s: got usage %d, %d conns; want %d, %d", tt.name, usage, conns, tt.usage, tt.conns)
+		}
+	}
+}
+
+func TestIsLoopbackHex(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"0100007F", true},  // 127.0.0.1
+		{"0200007F", true},  // 127.0.0.2
+		{"0000007F", true},  // 127.0.0.0
+		{"7F000001", false}, // 1.0.0.127
+		{"0A000001", false}, // 1.0.0.10
+		{"00000000", false},
+		{"00000000000000000000000001000000", true},  // ::1
+		{"0000000000000000FFFF00000100007F", true},  // ::ffff:127.0.0.1
+		{"0000000000000000FFFF00000A00000A", false}, // ::ffff:10.0.0.10
+		{"00000000000000000000000000000000", false}, // ::
+		{"B80D0120000000000000000001000000", false}, // 2001:db8::1
+		{"", false},
+		{"7F", false},
+	}
+	for _, tt := range tests {
+		if got := isLoopbackHex(tt.ip); got != tt.want {
+			t.Errorf("isLoopbackHex(%q) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestMeasureCPU(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	window := 10 * time.Minute
+	sampleAt := func(usage int64, ago time.Duration) string {
+		return fmt.Sprintf("%d@%d", usage, now.Add(-ago).Unix())
+	}
+
+	tests := []struct {
+		name       string
+		usage      int64
+		sample     string
+		resample   bool
+		complete   bool
+		millicores int64
+	}{
+		{name: "no previous sample", usage: 5_000_000, sample: "", resample: true},
+		{name: "malformed sample", usage: 5_000_000, sample: "garbage", resample: true},
+		{name: "counter went backwards", usage: 1_000, sample: sampleAt(5_000_000, window), resample: true},
+		{name: "sample from the future", usage: 5_000_000, sample: sampleAt(0, -time.Minute), resample: true},
+		{name: "window still open", usage: 5_000_000, sample: sampleAt(0, window-time.Second)},
+		{
+			// 60 CPU-seconds over 600 seconds is 100 millicores.
+			name: "window boundary", usage: 60_000_000, sample: sampleAt(0, window),
+			resample: true, complete: true, millicores: 100,
+		},
+		{
+			name: "past the window", usage: 2_000_000 + 24_000_000, sample: sampleAt(2_000_000, 2*window),
+			resample: true, complete: true, millicores: 20,
+		},
+		{
+			name: "unchanged counter", usage: 7_000_000, sample: sampleAt(7_000_000, window),
+			resample: true, complete: true, millicores: 0,
+		},
+	}
+	for _, tt := range tests {
+		got := measureCPU(tt.usage, tt.sample, now, window)
+		if got.resample != tt.resample || got.complete != tt.complete || got.millicores != tt.millicores {
+			t.Errorf("%s: measureCPU = %+v, want resample %v, complete %v, %dm",
+				tt.name, got, tt.resample, tt.complete, tt.millicores)
+		}
+		if !got.complete && got.reason == "" {
+			t.Errorf("%s: incomplete window without a reason", tt.name)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rathi/agentikube/internal/kube"
+	"github.com/rathi/agentikube/internal/output"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// outputFormat returns the validated value of the global --output flag.
+func outputFormat(cmd *cobra.Command) (string, error) {
+	format, _ := cmd.Flags().GetString("output")
+	return output.ParseFormat(format)
+}
+
+// placement holds the pods in the sandbox namespace and the nodes they run
+// on, keyed by name.
+type placement struct {
+	pods  map[string]*corev1.Pod
+	nodes map[string]*corev1.Node
+}
+
+// lookupPlacement lists pods in namespace and their nodes. Listing nodes
+// needs cluster-scoped access, so a failure there only drops node details.
+func lookupPlacement(ctx context.Context, client *kube.Client, namespace string) (*placement, error) {
+	pods, err := client.Clientset().CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("listing pods: %w", err)
+	}
+
+	p := &placement{
+		pods:  make(map[string]*corev1.Pod, len(pods.Items)),
+		nodes: map[string]*corev1.Node{},
+	}
+	for i := range pods.Items {
+		p.pods[pods.Items[i].Name] = &pods.Items[i]
+	}
+
+	nodes, err := client.Clientset().CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err == nil {
+		for i := range nodes.Items {
+			p.nodes[nodes.Items[i].Name] = &nodes.Items[i]
+		}
+	}
+	return p, nil
+}
+
+// sandboxResult builds the output record for a SandboxClaim. When p is nil,
+// pod and node details are left out.
+func sandboxResult(claim *unstructured.Unstructured, p *placement) output.Sandbox {
+	s := output.NewSandbox()
+	s.Handle = handleFromClaim(claim)
+	s.Name = claim.GetName()
+	s.Namespace = claim.GetNamespace()
+	s.Status = extractStatus(claim.Object)
+	s.Created = claim.GetCreationTimestamp().Time.UTC()
+	s.Age = formatAge(claim.GetCreationTimestamp().Time)
+	s.Labels = claim.GetLabels()
+	s.Description = claimAnnotation(claim.Object, descriptionAnnotation)
+	s.ReadyAfter = claimAnnotation(claim.Object, readyAfterAnnotation)
+
+	if podName := extractPodName(claim.Object); podName != "-" && !isPaused(claim.Object) {
+		s.Pod = podName
+	}
+	if p == nil || s.Pod == "" {
+		return s
+	}
+
+	pod, ok := p.pods[s.Pod]
+	if !ok {
+		return s
+	}
+	s.PodIP = pod.Status.PodIP
+	s.Node = pod.Spec.NodeName
+
+	if node, ok := p.nodes[s.Node]; ok {
+		s.InstanceType = node.Labels["node.kubernetes.io/instance-type"]
+		s.CapacityType = node.Labels["karpenter.sh/capacity-type"]
+	}
+	return s
+}
+
+// printSandboxes renders sandboxes in the given output format. The table
+// formats get one extra column per entry in labelColumns.
+func printSandboxes(w io.Writer, format string, items []output.Sandbox, labelColumns []string) error {
+	switch format {
+	case output.Table, output.Wide:
+		header := []string{"HANDLE", "STATUS", "AGE", "POD"}
+		if format == output.Wide {
+			header = append(header, "IP", "NODE", "INSTANCE-TYPE", "CAPACITY")
+		}
+		for _, key := range labelColumns {
+			header = append(header, labelColumnHeader(key))
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, s := range items {
+			fmt.Fprintln(tw, strings.Join(sandboxRow(format, s, labelColumns), "\t"))
+		}
+		return tw.Flush()
+	case output.Name:
+		for _, s := range items {
+			fmt.Fprintln(w, s.Handle)
+		}
+		return nil
+	default:
+		return output.Write(w, format, output.NewSandboxList(items))
+	}
+}
+
+// sandboxRow returns the table cells for s.
+func sandboxRow(format string, s output.Sandbox, labelColumns []string) []string {
+	row := []string{s.Handle, s.Status, s.Age, dash(s.Pod)}
+	if format == output.Wide {
+		row = append(row, dash(s.PodIP), dash(s.Node), dash(s.InstanceType), dash(s.CapacityType))
+	}
+	for _, key := range labelColumns {
+		row = append(row, dash(s.Labels[key]))
+	}
+	return row
+}
+
+// labelColumnHeader turns a label key such as example.com/team into a
+// column header (TEAM), as kubectl does for --label-columns.
+func labelColumnHeader(key string) string {
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		key = key[i+1:]
+	}
+	return strings.ToUpper(key)
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// stdoutFor returns where progress messages should go: stdout for the
+// human-readable formats, stderr when stdout carries structured output.
+func stdoutFor(format string) io.Writer {
+	if format == output.Table {
+		return os.Stdout
+	}
+	return os.Stderr
+}
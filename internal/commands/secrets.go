@@ -0,0 +1,311 @@
+package commands
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rathi/agentikube/internal/kube"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func NewSecretsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "secrets",
+		Short: "Manage the credentials of a sandbox",
+		Long: "Lists and edits the sandbox-<handle> Secret whose keys are exposed to the\n" +
+			"sandbox as environment variables. PROVIDER and PROVIDER_KEY hold the default\n" +
+			"provider; further providers are stored as <PROVIDER>_API_KEY. Values are never\n" +
+			"printed. Running processes only see changes after the pod restarts (--restart).",
+	}
+
+	cmd.AddCommand(
+		newSecretsListCmd(),
+		newSecretsSetCmd(),
+		newSecretsRotateCmd(),
+	)
+
+	return cmd
+}
+
+func newSecretsListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list <handle>",
+		Short: "List the keys stored for a sandbox",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			client, err := kube.NewClient()
+			if err != nil {
+				return fmt.Errorf("connecting to cluster: %w", err)
+			}
+
+			return printSecretKeys(ctx, client, cfg.Namespace, args[0])
+		},
+	}
+
+	return cmd
+}
+
+func newSecretsSetCmd() *cobra.Command {
+	var restart bool
+
+	cmd := &cobra.Command{
+		Use:   "set <handle> KEY=VALUE... [KEY-...]",
+		Short: "Set or remove keys in a sandbox's Secret",
+		Long: "Sets each KEY=VALUE in the sandbox-<handle> Secret and removes each KEY-.\n" +
+			"Values given here end up in shell history; use `secrets rotate` for API keys.",
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			handle := args[0]
+
+			changes := make(map[string]interface{}, len(args)-1)
+			for _, arg := range args[1:] {
+				if key, ok := strings.CutSuffix(arg, "-"); ok && !strings.Contains(arg, "=") {
+					if err := validateSecretKey(key); err != nil {
+						return err
+					}
+					changes[key] = nil
+					continue
+				}
+				key, value, ok := strings.Cut(arg, "=")
+				if !ok {
+					return fmt.Errorf("invalid argument %q: expected KEY=VALUE or KEY-", arg)
+				}
+				if err := validateSecretKey(key); err != nil {
+					return err
+				}
+				changes[key] = base64.StdEncoding.EncodeToString([]byte(value))
+			}
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			client, err := kube.NewClient()
+			if err != nil {
+				return fmt.Errorf("connecting to cluster: %w", err)
+			}
+
+			return updateSecret(ctx, client, cfg.Namespace, handle, changes, restart)
+		},
+	}
+
+	cmd.Flags().BoolVar(&restart, "restart", false, "restart the sandbox pod so the new values take effect")
+
+	return cmd
+}
+
+func newSecretsRotateCmd() *cobra.Command {
+	var provider string
+	var apiKey apiKeyFlags
+	var restart bool
+
+	cmd := &cobra.Command{
+		Use:   "rotate <handle>",
+		Short: "Replace a provider API key",
+		Long: "Replaces the API key of the sandbox's default provider (PROVIDER_KEY). With\n" +
+			"--provider, stores the key as <PROVIDER>_API_KEY instead, adding that provider\n" +
+			"if it is new; PROVIDER_KEY is updated too when it is the default provider.\n\n" +
+			"The key is read from --api-key-file, --api-key-stdin, SANDBOX_API_KEY or an\n" +
+			"interactive prompt.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			handle := args[0]
+
+			key, err := apiKey.resolve(cmd)
+			if err != nil {
+				return err
+			}
+			if key == "" {
+				return fmt.Errorf("no API key given (use --api-key-file, --api-key-stdin or %s)", apiKeyEnv)
+			}
+			encoded := base64.StdEncoding.EncodeToString([]byte(key))
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			client, err := kube.NewClient()
+			if err != nil {
+				return fmt.Errorf("connecting to cluster: %w", err)
+			}
+
+			ns := cfg.Namespace
+
+			changes := map[string]interface{}{}
+			if provider == "" {
+				changes["PROVIDER_KEY"] = encoded
+			} else {
+				changes[providerKeyName(provider)] = encoded
+
+				secret, err := getSecret(ctx, client, ns, handle)
+				if err != nil {
+					return err
+				}
+				if strings.EqualFold(string(secret.Data["PROVIDER"]), provider) {
+					changes["PROVIDER_KEY"] = encoded
+				}
+			}
+
+			return updateSecret(ctx, client, ns, handle, changes, restart)
+		},
+	}
+
+	cmd.Flags().StringVar(&provider, "provider", "", "provider whose key to replace (default: the sandbox's default provider)")
+	apiKey.register(cmd, "new API key")
+	cmd.Flags().BoolVar(&restart, "restart", false, "restart the sandbox pod so the new key takes effect")
+
+	return cmd
+}
+
+// updateSecret merge-patches the data of the sandbox-<handle> Secret. A nil
+// value removes the key. With restart, the sandbox pod is recreated
+// afterwards so its environment picks up the change.
+func updateSecret(ctx context.Context, client *kube.Client, ns, handle string, changes map[string]interface{}, restart bool) error {
+	claim, err := getClaim(ctx, client, ns, handle)
+	if err != nil {
+		return err
+	}
+	name := claim.GetName()
+
+	patch, err := json.Marshal(map[string]interface{}{"data": changes})
+	if err != nil {
+		return err
+	}
+	_, err = client.Clientset().CoreV1().Secrets(ns).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("updating Secret %q: %w", name, err)
+	}
+
+	keys := make([]string, 0, len(changes))
+	for k := range changes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fmt.Printf("[ok] Secret %q updated (%s)\n", name, strings.Join(keys, ", "))
+
+	if !restart {
+		fmt.Println("running processes keep the old values until the pod restarts (use --restart)")
+		return nil
+	}
+	return restartSandboxPod(ctx, client, ns, handle)
+}
+
+// printSecretKeys prints the keys of the sandbox-<handle> Secret and the
+// size of each value.
+func printSecretKeys(ctx context.Context, client *kube.Client, namespace, handle string) error {
+	secret, err := getSecret(ctx, client, namespace, handle)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(secret.Data))
+	for k := range secret.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tSIZE")
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s\t%s\n", k, formatBytes(int64(len(secret.Data[k]))))
+	}
+	w.Flush()
+
+	if provider := string(secret.Data["PROVIDER"]); provider != "" {
+		fmt.Printf("\ndefault provider: %s\n", provider)
+	}
+	return nil
+}
+
+// getSecret returns the Secret of the sandbox for handle.
+func getSecret(ctx context.Context, client *kube.Client, namespace, handle string) (*corev1.Secret, error) {
+	claim, err := getClaim(ctx, client, namespace, handle)
+	if err != nil {
+		return nil, err
+	}
+	name := claim.GetName()
+	secret, err := client.Clientset().CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("getting Secret %q: %w", name, err)
+	}
+	return secret, nil
+}
+
+// restartSandboxPod deletes the sandbox pod and waits for its replacement to
+// become ready. The workspace lives on the sandbox PVC and is kept.
+func restartSandboxPod(ctx context.Context, client *kube.Client, namespace, handle string) error {
+	claim, err := getClaim(ctx, client, namespace, handle)
+	if err != nil {
+		return err
+	}
+	if isPaused(claim.Object) {
+		fmt.Printf("sandbox %q is paused; it picks up the change when resumed\n", handle)
+		return nil
+	}
+
+	old, err := currentPod(ctx, client, namespace, claim)
+	if err != nil {
+		return err
+	}
+	if old.uid == "" {
+		return fmt.Errorf("sandbox %q %w", handle, errNoPod)
+	}
+	if err := client.Clientset().CoreV1().Pods(namespace).Delete(ctx, old.name, metav1.DeleteOptions{}); err != nil {
+		return fmt.Errorf("deleting pod %s: %w", old.name, err)
+	}
+	fmt.Printf("[ok] pod %s deleted\n", old.name)
+
+	fmt.Println("waiting for sandbox to be ready...")
+	if _, err := waitForNewPod(ctx, client, namespace, handle, old, defaultReadyTimeout); err != nil {
+		return err
+	}
+	if err := syncPodMetadata(ctx, client, namespace, handle); err != nil {
+		fmt.Printf("[warn] %v\n", err)
+	}
+	fmt.Printf("[ok] sandbox %q restarted\n", handle)
+	return nil
+}
+
+func validateSecretKey(key string) error {
+	if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+		return fmt.Errorf("invalid key %q: %s", key, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// providerKeyName returns the Secret key holding the API key of provider,
+// e.g. ANTHROPIC_API_KEY.
+func providerKeyName(provider string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, provider)
+	return name + "_API_KEY"
+}
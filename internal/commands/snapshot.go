@@ -0,0 +1,292 @@
+package commands
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rathi/agentikube/internal/config"
+	"github.com/rathi/agentikube/internal/kube"
+	"github.com/rathi/agentikube/internal/snapshot"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func NewSnapshotCmd() *cobra.Command {
+	var name string
+
+	cmd := &cobra.Command{
+		Use:   "snapshot <handle>",
+		Short: "Snapshot a sandbox workspace",
+		Long: "Archives the sandbox workspace (sandbox.mountPath) through the exec channel and\n" +
+			"stores it as a gzip tarball in snapshots.location, a local directory or an\n" +
+			"S3-compatible bucket. Use `snapshot list` to see stored snapshots.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			handle := args[0]
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			if name == "" {
+				name = fmt.Sprintf("%s-%s", handle, time.Now().UTC().Format("20060102-150405"))
+			}
+			if err := snapshot.ValidateName(name); err != nil {
+				return err
+			}
+
+			store, err := openSnapshotStore(cfg)
+			if err != nil {
+				return err
+			}
+
+			client, err := kube.NewClient()
+			if err != nil {
+				return fmt.Errorf("connecting to cluster: %w", err)
+			}
+
+			ns := cfg.Namespace
+
+			podName, err := resolvePod(ctx, client, ns, handle)
+			if err != nil {
+				return err
+			}
+
+			meta, err := takeSnapshot(ctx, client, cfg, store, handle, podName, name)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("[ok] snapshot %q saved to %s (%s)\n", meta.Name, store.Location(), formatBytes(meta.Size))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "snapshot name (default: <handle>-<timestamp>)")
+
+	cmd.AddCommand(newSnapshotListCmd())
+
+	return cmd
+}
+
+func newSnapshotListCmd() *cobra.Command {
+	var handle string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List stored snapshots",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			store, err := openSnapshotStore(cfg)
+			if err != nil {
+				return err
+			}
+
+			metas, err := store.List(ctx)
+			if err != nil {
+				return fmt.Errorf("listing snapshots in %s: %w", store.Location(), err)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tHANDLE\tIMAGE\tAGE\tSIZE")
+			for _, m := range metas {
+				if handle != "" && m.Handle != handle {
+					continue
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", m.Name, m.Handle, m.Image, formatAge(m.Created), formatBytes(m.Size))
+			}
+			w.Flush()
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&handle, "handle", "", "only show snapshots of this handle")
+
+	return cmd
+}
+
+func NewRestoreCmd() *cobra.Command {
+	var from string
+	var clean bool
+
+	cmd := &cobra.Command{
+		Use:   "restore <handle> --from <snapshot>",
+		Short: "Restore a sandbox workspace from a snapshot",
+		Long: "Unpacks a stored snapshot into the sandbox workspace. Existing files are\n" +
+			"overwritten; use --clean to empty the workspace first.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			handle := args[0]
+
+			if err := snapshot.ValidateName(from); err != nil {
+				return err
+			}
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			store, err := openSnapshotStore(cfg)
+			if err != nil {
+				return err
+			}
+
+			client, err := kube.NewClient()
+			if err != nil {
+				return fmt.Errorf("connecting to cluster: %w", err)
+			}
+
+			ns := cfg.Namespace
+
+			if err := wakeIfHibernated(ctx, client, ns, handle, "restore"); err != nil {
+				return err
+			}
+			podName, err := resolvePod(ctx, client, ns, handle)
+			if err != nil {
+				return err
+			}
+
+			meta, body, err := store.Get(ctx, from)
+			if err != nil {
+				return err
+			}
+			defer body.Close()
+
+			if meta.Handle != handle {
+				fmt.Printf("[warn] snapshot %q was taken from sandbox %q\n", meta.Name, meta.Handle)
+			}
+			if image := podImage(ctx, client, ns, podName); image != "" && meta.Image != "" && image != meta.Image {
+				fmt.Printf("[warn] snapshot image %s differs from running image %s\n", meta.Image, image)
+			}
+
+			if clean {
+				if err := cleanWorkspace(ctx, client, ns, podName, cfg.Sandbox.MountPath); err != nil {
+					return err
+				}
+				fmt.Printf("[ok] workspace %s emptied\n", cfg.Sandbox.MountPath)
+			}
+
+			prog := newProgress("restoring", meta.Size)
+			gz, err := gzip.NewReader(io.TeeReader(body, prog))
+			if err != nil {
+				return fmt.Errorf("reading snapshot %q: %w", meta.Name, err)
+			}
+			err = streamToPod(ctx, client, ns, podName, cfg.Sandbox.MountPath, gz)
+			prog.Done()
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("[ok] snapshot %q restored into sandbox %q\n", meta.Name, handle)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&from, "from", "", "name of the snapshot to restore (required)")
+	cmd.Flags().BoolVar(&clean, "clean", false, "delete existing workspace files before restoring")
+	cmd.MarkFlagRequired("from")
+
+	return cmd
+}
+
+func openSnapshotStore(cfg *config.Config) (snapshot.Store, error) {
+	return snapshot.Open(cfg.Snapshots.Location, cfg.Snapshots.Endpoint, cfg.Snapshots.Region)
+}
+
+// takeSnapshot archives the workspace of podName into a temporary file and
+// uploads it to store under name.
+func takeSnapshot(ctx context.Context, client *kube.Client, cfg *config.Config, store snapshot.Store, handle, podName, name string) (snapshot.Meta, error) {
+	tmp, err := os.CreateTemp("", "agentikube-snapshot-*.tar.gz")
+	if err != nil {
+		return snapshot.Meta{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	prog := newProgress("archiving", 0)
+	gz := gzip.NewWriter(tmp)
+	err = streamFromPod(ctx, client, cfg.Namespace, podName, cfg.Sandbox.MountPath, ".", io.MultiWriter(gz, prog))
+	prog.Done()
+	if err != nil {
+		return snapshot.Meta{}, fmt.Errorf("archiving workspace: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return snapshot.Meta{}, err
+	}
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return snapshot.Meta{}, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return snapshot.Meta{}, err
+	}
+
+	image := podImage(ctx, client, cfg.Namespace, podName)
+	if image == "" {
+		image = cfg.Sandbox.Image
+	}
+
+	meta := snapshot.Meta{
+		Name:    name,
+		Handle:  handle,
+		Image:   image,
+		Created: time.Now().UTC(),
+		Size:    size,
+	}
+
+	uploadProg := newProgress("uploading", size)
+	err = store.Put(ctx, meta, io.TeeReader(tmp, uploadProg))
+	uploadProg.Done()
+	if err != nil {
+		return snapshot.Meta{}, fmt.Errorf("storing snapshot %q: %w", name, err)
+	}
+	return meta, nil
+}
+
+// podImage returns the image of the sandbox container in podName, or "" if
+// the pod cannot be read.
+func podImage(ctx context.Context, client *kube.Client, namespace, podName string) string {
+	pod, err := client.Clientset().CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil || len(pod.Spec.Containers) == 0 {
+		return ""
+	}
+	for _, c := range pod.Spec.Containers {
+		if c.Name == "sandbox" {
+			return c.Image
+		}
+	}
+	return pod.Spec.Containers[0].Image
+}
+
+// cleanWorkspace deletes everything inside dir in the pod, keeping dir.
+func cleanWorkspace(ctx context.Context, client *kube.Client, namespace, podName, dir string) error {
+	var stderr bytes.Buffer
+	err := client.Exec(ctx, kube.ExecOptions{
+		Namespace: namespace,
+		Pod:       podName,
+		Command:   []string{"sh", "-c", `cd "$1" && rm -rf -- ./* ./.[!.]* ./..?*`, "sh", dir},
+		Stderr:    &stderr,
+	})
+	if err != nil {
+		return fmt.Errorf("emptying workspace %s: %w", dir, remoteError(err, &stderr))
+	}
+	return nil
+}
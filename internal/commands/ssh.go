@@ -6,7 +6,6 @@ import (
 
 	"github.com/rathi/agentikube/internal/kube"
 	"github.com/spf13/cobra"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func NewSSHCmd() *cobra.Command {
@@ -30,20 +29,22 @@ func NewSSHCmd() *cobra.Command {
 			}
 
 			ns := cfg.Namespace
-			name := "sandbox-" + handle
 
-			claim, err := client.Dynamic().Resource(sandboxClaimGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
-			if err != nil {
-				return fmt.Errorf("getting SandboxClaim %q: %w", name, err)
+			if err := wakeIfHibernated(ctx, client, ns, handle, "ssh"); err != nil {
+				return err
 			}
 
-			podName := extractPodName(claim.Object)
-			if podName == "-" || podName == "" {
-				return fmt.Errorf("sandbox %q does not have a pod assigned yet", handle)
+			podName, err := resolvePod(ctx, client, ns, handle)
+			if err != nil {
+				return err
 			}
 
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			keepActive(ctx, client, ns, handle)
+
 			fmt.Printf("connecting to pod %s...\n", podName)
-			return kube.Exec(ns, podName, []string{"/bin/sh"})
+			return client.Shell(ctx, ns, podName, []string{"/bin/sh"})
 		},
 	}
 
@@ -0,0 +1,153 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rathi/agentikube/internal/kube"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	sshConfigBegin = "# BEGIN agentikube"
+	sshConfigEnd   = "# END agentikube"
+)
+
+func NewSSHConfigCmd() *cobra.Command {
+	var file string
+	var user string
+	var identity string
+	var port int
+
+	cmd := &cobra.Command{
+		Use:   "ssh-config",
+		Short: "Generate OpenSSH Host entries for all sandboxes",
+		Long: "Prints a `Host sandbox-<handle>` entry for every sandbox, each using\n" +
+			"`agentikube ssh-proxy` as its ProxyCommand, so ssh, scp, rsync and editor\n" +
+			"remote-SSH extensions can connect. With --file the entries replace the\n" +
+			"agentikube block in that file, leaving the rest of it untouched.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			client, err := kube.NewClient()
+			if err != nil {
+				return fmt.Errorf("connecting to cluster: %w", err)
+			}
+
+			list, err := client.Dynamic().Resource(sandboxClaimGVR).Namespace(cfg.Namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return fmt.Errorf("listing SandboxClaims: %w", err)
+			}
+
+			exe, err := os.Executable()
+			if err != nil {
+				return fmt.Errorf("locating agentikube binary: %w", err)
+			}
+			cfgPath, _ := cmd.Flags().GetString("config")
+			cfgPath, err = filepath.Abs(cfgPath)
+			if err != nil {
+				return err
+			}
+
+			handles := make([]string, 0, len(list.Items))
+			for _, item := range list.Items {
+				handles = append(handles, handleFromClaim(&item))
+			}
+			block := sshConfigBlock(handles, exe, cfgPath, user, identity, port)
+
+			if file == "" {
+				fmt.Print(block)
+				return nil
+			}
+
+			if err := writeSSHConfigBlock(file, block); err != nil {
+				return err
+			}
+			fmt.Printf("[ok] wrote %d hosts to %s\n", len(list.Items), file)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&file, "file", "", "SSH config file to update (default: print to stdout)")
+	cmd.Flags().StringVar(&user, "user", "", "remote user for the Host entries")
+	cmd.Flags().StringVar(&identity, "identity", "", "IdentityFile for the Host entries")
+	cmd.Flags().IntVar(&port, "port", 2222, "SSH port inside the sandbox")
+
+	return cmd
+}
+
+// sshConfigBlock returns the agentikube block with one Host entry per
+// handle, proxied through exe.
+func sshConfigBlock(handles []string, exe, cfgPath, user, identity string, port int) string {
+	var b strings.Builder
+	b.WriteString(sshConfigBegin + "\n")
+	for _, handle := range handles {
+		host := "sandbox-" + handle
+		fmt.Fprintf(&b, "Host %s\n", host)
+		fmt.Fprintf(&b, "  HostName %s\n", host)
+		// Pods are replaced over time, so pin the host key to the
+		// handle rather than to an address.
+		fmt.Fprintf(&b, "  HostKeyAlias %s\n", host)
+		if user != "" {
+			fmt.Fprintf(&b, "  User %s\n", user)
+		}
+		if identity != "" {
+			fmt.Fprintf(&b, "  IdentityFile %s\n", identity)
+		}
+		fmt.Fprintf(&b, "  ProxyCommand %s --config %s ssh-proxy %s --port %d\n",
+			proxyCommandArg(exe), proxyCommandArg(cfgPath), handle, port)
+	}
+	b.WriteString(sshConfigEnd + "\n")
+	return b.String()
+}
+
+// proxyCommandArg quotes s for a ProxyCommand. ssh expands % tokens and
+// then runs the command with the user's shell, so % is doubled and s is
+// single-quoted, ending the quote around each ' and escaping it.
+func proxyCommandArg(s string) string {
+	s = strings.ReplaceAll(s, "%", "%%")
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// writeSSHConfigBlock replaces the agentikube block in file with block, or
+// appends it if the file has none. The file is created if missing.
+func writeSSHConfigBlock(file, block string) error {
+	data, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading %s: %w", file, err)
+	}
+	content := string(data)
+
+	start := strings.Index(content, sshConfigBegin)
+	end := strings.Index(content, sshConfigEnd)
+	switch {
+	case start >= 0 && end > start:
+		end += len(sshConfigEnd)
+		if end < len(content) && content[end] == '\n' {
+			end++
+		}
+		content = content[:start] + block + content[end:]
+	case content == "":
+		content = block
+	default:
+		if !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		content += "\n" + block
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(file, []byte(content), 0o600)
+}
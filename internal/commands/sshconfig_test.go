@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestSSHConfigBlock(t *testing.T) {
+	got := sshConfigBlock([]string{"demo", "eng-42"}, "/opt/my tools/agentikube", "/home/o'brien/agentikube.yaml", "dev", "~/.ssh/id_ed25519", 2222)
+	want := `# BEGIN agentikube
+Host sandbox-demo
+  HostName sandbox-demo
+  HostKeyAlias sandbox-demo
+  User dev
+  IdentityFile ~/.ssh/id_ed25519
+  ProxyCommand '/opt/my tools/agentikube' --config '/home/o'\''brien/agentikube.yaml' ssh-proxy demo --port 2222
+Host sandbox-eng-42
+  HostName sandbox-eng-42
+  HostKeyAlias sandbox-eng-42
+  User dev
+  IdentityFile ~/.ssh/id_ed25519
+  ProxyCommand '/opt/my tools/agentikube' --config '/home/o'\''brien/agentikube.yaml' ssh-proxy eng-42 --port 2222
+# END agentikube
+`
+	if got != want {
+		t.Errorf("sshConfigBlock =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestProxyCommandArg(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh in PATH")
+	}
+	for _, s := range []string{
+		"/usr/local/bin/agentikube",
+		"/opt/my tools/agentikube",
+		"/home/o'brien/a b.yaml",
+		`/tmp/$HOME/"quoted"/back\slash/$(id)`,
+		"''",
+	} {
+		// Undo ssh's %% expansion, then let the shell parse the word.
+		word := strings.ReplaceAll(proxyCommandArg(s), "%%", "%")
+		out, err := exec.Command(sh, "-c", "printf %s "+word).Output()
+		if err != nil {
+			t.Fatalf("sh -c printf %s: %v", word, err)
+		}
+		if string(out) != s {
+			t.Errorf("proxyCommandArg(%q) = %s, which the shell reads as %q", s, word, out)
+		}
+	}
+	if got := proxyCommandArg("/tmp/100%/x"); got != "'/tmp/100%%/x'" {
+		t.Errorf("proxyCommandArg did not escape %%: %s", got)
+	}
+}
@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rathi/agentikube/internal/kube"
+	"github.com/spf13/cobra"
+)
+
+func NewSSHProxyCmd() *cobra.Command {
+	var port int
+
+	cmd := &cobra.Command{
+		Use:   "ssh-proxy <handle>",
+		Short: "Tunnel stdio to a sandbox's SSH port",
+		Long: "Connects stdin/stdout to the SSH server in the sandbox through a port-forward\n" +
+			"stream, for use as an OpenSSH ProxyCommand. See ssh-config to generate the\n" +
+			"matching Host entries.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			handle := args[0]
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			client, err := kube.NewClient()
+			if err != nil {
+				return fmt.Errorf("connecting to cluster: %w", err)
+			}
+
+			ns := cfg.Namespace
+
+			if err := wakeIfHibernated(ctx, client, ns, handle, "ssh-proxy"); err != nil {
+				return err
+			}
+			podName, err := resolvePod(ctx, client, ns, handle)
+			if err != nil {
+				return err
+			}
+
+			conn, err := client.DialPort(ns, podName, port)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			keepActive(ctx, client, ns, handle)
+
+			// The session ends when the remote side closes; local EOF on
+			// stdin alone must not cut off output still in flight.
+			go io.Copy(conn, os.Stdin)
+			if _, err := io.Copy(os.Stdout, conn); err != nil {
+				return fmt.Errorf("ssh-proxy to %s: %w", handle, err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&port, "port", 2222, "SSH port inside the sandbox")
+
+	return cmd
+}
@@ -3,20 +3,38 @@ package commands
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
 
 	"github.com/rathi/agentikube/internal/kube"
+	"github.com/rathi/agentikube/internal/output"
 	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func NewStatusCmd() *cobra.Command {
+	var watch bool
+
 	cmd := &cobra.Command{
 		Use:   "status",
 		Short: "Show cluster and sandbox status",
-		Long:  "Displays warm pool status, sandbox counts, and compute node information.",
+		Long: "Displays warm pool status, sandbox counts, and compute node information.\n" +
+			"With --watch the report is refreshed as sandboxes and pods change.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := context.Background()
 
+			format, err := outputFormat(cmd)
+			if err != nil {
+				return err
+			}
+			if format == output.Name || format == output.Wide {
+				return fmt.Errorf("status does not support -o %s", format)
+			}
+			if watch && format != output.Table {
+				return fmt.Errorf("--watch does not support -o %s", format)
+			}
+
 			cfg, err := loadConfig(cmd)
 			if err != nil {
 				return err
@@ -27,53 +45,105 @@ func NewStatusCmd() *cobra.Command {
 				return fmt.Errorf("connecting to cluster: %w", err)
 			}
 
-			ns := cfg.Namespace
+			karpenter := cfg.Compute.Type == "karpenter"
 
-			// Warm pool status
-			wp, err := client.Dynamic().Resource(sandboxWarmPoolGVR).Namespace(ns).Get(ctx, "sandbox-warm-pool", metav1.GetOptions{})
-			if err != nil {
-				fmt.Printf("warm pool: not found (%v)\n", err)
-			} else {
-				spec, _ := wp.Object["spec"].(map[string]interface{})
-				status, _ := wp.Object["status"].(map[string]interface{})
-
-				replicas := getInt64(spec, "replicas")
-				readyReplicas := getInt64(status, "readyReplicas")
-				pendingReplicas := getInt64(status, "pendingReplicas")
-
-				fmt.Println("warm pool:")
-				fmt.Printf("  desired:  %d\n", replicas)
-				fmt.Printf("  ready:    %d\n", readyReplicas)
-				fmt.Printf("  pending:  %d\n", pendingReplicas)
+			if watch {
+				ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+				defer stop()
+				return watchStatus(ctx, client, cfg.Namespace, karpenter)
 			}
 
-			// Sandbox count
-			claims, err := client.Dynamic().Resource(sandboxClaimGVR).Namespace(ns).List(ctx, metav1.ListOptions{})
-			if err != nil {
-				fmt.Printf("\nsandboxes: error listing (%v)\n", err)
-			} else {
-				fmt.Printf("\nsandboxes: %d\n", len(claims.Items))
-			}
+			st := collectStatus(ctx, client, cfg.Namespace, karpenter)
 
-			// Karpenter nodes (if applicable)
-			if cfg.Compute.Type == "karpenter" {
-				nodes, err := client.Clientset().CoreV1().Nodes().List(ctx, metav1.ListOptions{
-					LabelSelector: "karpenter.sh/nodepool",
-				})
-				if err != nil {
-					fmt.Printf("\nkarpenter nodes: error listing (%v)\n", err)
-				} else {
-					fmt.Printf("\nkarpenter nodes: %d\n", len(nodes.Items))
-				}
+			if format != output.Table {
+				return output.Write(os.Stdout, format, st)
 			}
-
+			printStatus(os.Stdout, st)
 			return nil
 		},
 	}
 
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "keep watching for changes")
+
 	return cmd
 }
 
+// collectStatus gathers warm pool, sandbox and node counts. Failures are
+// recorded per section so the rest of the report is still useful.
+func collectStatus(ctx context.Context, client *kube.Client, ns string, karpenter bool) output.Status {
+	st := output.NewStatus()
+	st.Namespace = ns
+
+	// Warm pool status
+	wp, err := client.Dynamic().Resource(sandboxWarmPoolGVR).Namespace(ns).Get(ctx, warmPoolName, metav1.GetOptions{})
+	if err != nil {
+		st.WarmPool.Error = fmt.Sprintf("not found (%v)", err)
+	} else {
+		spec, _ := wp.Object["spec"].(map[string]interface{})
+		status, _ := wp.Object["status"].(map[string]interface{})
+
+		st.WarmPool.Desired = getInt64(spec, "replicas")
+		st.WarmPool.Ready = getInt64(status, "readyReplicas")
+		st.WarmPool.Pending = getInt64(status, "pendingReplicas")
+	}
+
+	// Sandbox count
+	claims, err := client.Dynamic().Resource(sandboxClaimGVR).Namespace(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		st.Sandboxes.Error = fmt.Sprintf("error listing (%v)", err)
+	} else {
+		st.Sandboxes.Total = len(claims.Items)
+		for _, item := range claims.Items {
+			if isPaused(item.Object) {
+				st.Sandboxes.Paused++
+			}
+		}
+		st.Sandboxes.Running = st.Sandboxes.Total - st.Sandboxes.Paused
+	}
+
+	// Karpenter nodes (if applicable)
+	if karpenter {
+		st.Nodes = &output.NodeCounts{}
+		nodes, err := client.Clientset().CoreV1().Nodes().List(ctx, metav1.ListOptions{
+			LabelSelector: "karpenter.sh/nodepool",
+		})
+		if err != nil {
+			st.Nodes.Error = fmt.Sprintf("error listing (%v)", err)
+		} else {
+			st.Nodes.Karpenter = len(nodes.Items)
+		}
+	}
+
+	return st
+}
+
+func printStatus(w io.Writer, st output.Status) {
+	if st.WarmPool.Error != "" {
+		fmt.Fprintf(w, "warm pool: %s\n", st.WarmPool.Error)
+	} else {
+		fmt.Fprintln(w, "warm pool:")
+		fmt.Fprintf(w, "  desired:  %d\n", st.WarmPool.Desired)
+		fmt.Fprintf(w, "  ready:    %d\n", st.WarmPool.Ready)
+		fmt.Fprintf(w, "  pending:  %d\n", st.WarmPool.Pending)
+	}
+
+	if st.Sandboxes.Error != "" {
+		fmt.Fprintf(w, "\nsandboxes: %s\n", st.Sandboxes.Error)
+	} else {
+		fmt.Fprintf(w, "\nsandboxes: %d\n", st.Sandboxes.Total)
+		fmt.Fprintf(w, "  running:  %d\n", st.Sandboxes.Running)
+		fmt.Fprintf(w, "  paused:   %d\n", st.Sandboxes.Paused)
+	}
+
+	if st.Nodes != nil {
+		if st.Nodes.Error != "" {
+			fmt.Fprintf(w, "\nkarpenter nodes: %s\n", st.Nodes.Error)
+		} else {
+			fmt.Fprintf(w, "\nkarpenter nodes: %d\n", st.Nodes.Karpenter)
+		}
+	}
+}
+
 func getInt64(m map[string]interface{}, key string) int64 {
 	if m == nil {
 		return 0
@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rathi/agentikube/internal/kube"
+)
+
+// streamFromPod writes a tar archive of dir/name inside the pod to w.
+func streamFromPod(ctx context.Context, client *kube.Client, namespace, podName, dir, name string, w io.Writer) error {
+	var stderr bytes.Buffer
+	err := client.Exec(ctx, kube.ExecOptions{
+		Namespace: namespace,
+		Pod:       podName,
+		Command:   []string{"tar", "-cf", "-", "-C", dir, name},
+		Stdout:    w,
+		Stderr:    &stderr,
+	})
+	if err != nil {
+		return fmt.Errorf("remote tar failed: %w", remoteError(err, &stderr))
+	}
+	return nil
+}
+
+// streamToPod unpacks the tar archive read from r into dir inside the pod,
+// creating dir if needed.
+func streamToPod(ctx context.Context, client *kube.Client, namespace, podName, dir string, r io.Reader) error {
+	var stderr bytes.Buffer
+	err := client.Exec(ctx, kube.ExecOptions{
+		Namespace: namespace,
+		Pod:       podName,
+		Command:   []string{"sh", "-c", `mkdir -p "$1" && tar -xmf - -C "$1"`, "sh", dir},
+		Stdin:     r,
+		Stderr:    &stderr,
+	})
+	if err != nil {
+		return fmt.Errorf("remote tar failed: %w", remoteError(err, &stderr))
+	}
+	return nil
+}
+
+// remoteError replaces a bare exit status with the command's stderr output,
+// which is far more useful to the user.
+func remoteError(err error, stderr *bytes.Buffer) error {
+	var exitErr *kube.ExitError
+	if errors.As(err, &exitErr) {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return errors.New(msg)
+		}
+	}
+	return err
+}
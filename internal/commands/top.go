@@ -0,0 +1,341 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rathi/agentikube/internal/kube"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var podMetricsGVR = schema.GroupVersionResource{
+	Group:    "metrics.k8s.io",
+	Version:  "v1beta1",
+	Resource: "pods",
+}
+
+// errNoMetrics is returned when the cluster does not serve the metrics API.
+var errNoMetrics = errors.New("the metrics API (metrics.k8s.io) is not available; install metrics-server: " +
+	"kubectl apply -f https://github.com/kubernetes-sigs/metrics-server/releases/latest/download/components.yaml")
+
+// topSortKeys are the values accepted by `top --sort`.
+var topSortKeys = []string{"cpu", "memory", "cpu%", "memory%", "handle"}
+
+func NewTopCmd() *cobra.Command {
+	var sortBy string
+	var watch bool
+	var interval time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "top [handle...]",
+		Short: "Show CPU and memory usage of sandboxes",
+		Long: "Shows the CPU and memory each sandbox uses, read from the metrics API, next to\n" +
+			"the requests and limits of its pod and as a percentage of the limit. Requires\n" +
+			"metrics-server in the cluster.\n\n" +
+			"Rows are sorted by --sort: cpu, memory, cpu% or memory% (highest first) or\n" +
+			"handle. With --watch the table is refreshed every --interval.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !slices.Contains(topSortKeys, sortBy) {
+				return fmt.Errorf("unknown --sort %q: expected one of %v", sortBy, topSortKeys)
+			}
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			client, err := kube.NewClient()
+			if err != nil {
+				return fmt.Errorf("connecting to cluster: %w", err)
+			}
+
+			if !watch {
+				rows, err := collectTop(context.Background(), client, cfg.Namespace, args)
+				if err != nil {
+					return err
+				}
+				sortTop(rows, sortBy)
+				printTop(os.Stdout, rows)
+				return nil
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			return watchTop(ctx, client, cfg.Namespace, args, sortBy, interval)
+		},
+	}
+
+	cmd.Flags().StringVar(&sortBy, "sort", "cpu", "sort by cpu, memory, cpu%, memory% or handle")
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "keep refreshing the table")
+	cmd.Flags().DurationVar(&interval, "interval", 10*time.Second, "refresh interval for --watch")
+
+	return cmd
+}
+
+// topRow is the usage of one sandbox. Values that are unknown, such as the
+// usage of a paused sandbox, are nil.
+type topRow struct {
+	handle string
+	pod    string
+
+	cpu, cpuRequest, cpuLimit    *resource.Quantity
+	memory, memRequest, memLimit *resource.Quantity
+}
+
+// podUsage is the CPU and memory a pod uses, summed over its containers.
+type podUsage struct {
+	cpu    resource.Quantity
+	memory resource.Quantity
+}
+
+// fetchPodMetrics reads the usage of every pod in namespace from the metrics
+// API. It takes a dynamic client so any server that serves metrics.k8s.io,
+// including a fake one, can back it.
+func fetchPodMetrics(ctx context.Context, dyn dynamic.Interface, namespace string) (map[string]podUsage, error) {
+	list, err := dyn.Resource(podMetricsGVR).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		return nil, errNoMetrics
+	case apierrors.IsServiceUnavailable(err):
+		return nil, fmt.Errorf("metrics-server is installed but not serving yet, try again shortly: %w", err)
+	case err != nil:
+		return nil, fmt.Errorf("reading pod metrics: %w", err)
+	}
+
+	usage := make(map[string]podUsage, len(list.Items))
+	for _, item := range list.Items {
+		usage[item.GetName()] = containerUsage(&item)
+	}
+	return usage, nil
+}
+
+func containerUsage(m *unstructured.Unstructured) podUsage {
+	var u podUsage
+	containers, _, _ := unstructured.NestedSlice(m.Object, "containers")
+	for _, c := range containers {
+		container, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		values, _, _ := unstructured.NestedStringMap(container, "usage")
+		if q, err := resource.ParseQuantity(values["cpu"]); err == nil {
+			u.cpu.Add(q)
+		}
+		if q, err := resource.ParseQuantity(values["memory"]); err == nil {
+			u.memory.Add(q)
+		}
+	}
+	return u
+}
+
+// collectTop builds a row for every sandbox, or for handles if given.
+func collectTop(ctx context.Context, client *kube.Client, namespace string, handles []string) ([]topRow, error) {
+	usage, err := fetchPodMetrics(ctx, client.Dynamic(), namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := client.Dynamic().Resource(sandboxClaimGVR).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("listing SandboxClaims: %w", err)
+	}
+	pods, err := client.Clientset().CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("listing pods: %w", err)
+	}
+	byName := make(map[string]*corev1.Pod, len(pods.Items))
+	for i := range pods.Items {
+		byName[pods.Items[i].Name] = &pods.Items[i]
+	}
+
+	var rows []topRow
+	for _, claim := range claims.Items {
+		handle := handleFromClaim(&claim)
+		if len(handles) > 0 && !slices.Contains(handles, handle) {
+			continue
+		}
+		row := topRow{handle: handle, pod: "-"}
+
+		podName := extractPodName(claim.Object)
+		if pod, ok := byName[podName]; ok && !isPaused(claim.Object) {
+			row.pod = pod.Name
+			requests, limits := podResources(pod)
+			row.cpuRequest, row.cpuLimit = quantityOf(requests, corev1.ResourceCPU), quantityOf(limits, corev1.ResourceCPU)
+			row.memRequest, row.memLimit = quantityOf(requests, corev1.ResourceMemory), quantityOf(limits, corev1.ResourceMemory)
+			if u, ok := usage[pod.Name]; ok {
+				row.cpu, row.memory = &u.cpu, &u.memory
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	for _, h := range handles {
+		if !slices.ContainsFunc(rows, func(r topRow) bool { return r.handle == h }) {
+			return nil, fmt.Errorf("sandbox %q not found", h)
+		}
+	}
+	return rows, nil
+}
+
+// podResources sums the requests and limits of a pod's containers.
+func podResources(pod *corev1.Pod) (corev1.ResourceList, corev1.ResourceList) {
+	requests, limits := corev1.ResourceList{}, corev1.ResourceList{}
+	for _, c := range pod.Spec.Containers {
+		for name, q := range c.Resources.Requests {
+			sum := requests[name]
+			sum.Add(q)
+			requests[name] = sum
+		}
+		for name, q := range c.Resources.Limits {
+			sum := limits[name]
+			sum.Add(q)
+			limits[name] = sum
+		}
+	}
+	return requests, limits
+}
+
+func quantityOf(list corev1.ResourceList, name corev1.ResourceName) *resource.Quantity {
+	q, ok := list[name]
+	if !ok {
+		return nil
+	}
+	return &q
+}
+
+// percentOf returns usage as a percentage of limit, or -1 if either is
+// unknown.
+func percentOf(usage, limit *resource.Quantity) float64 {
+	if usage == nil || limit == nil || limit.IsZero() {
+		return -1
+	}
+	return float64(usage.MilliValue()) / float64(limit.MilliValue()) * 100
+}
+
+// sortTop orders rows by key. Numeric keys sort highest first, with unknown
+// values last.
+func sortTop(rows []topRow, key string) {
+	value := func(r topRow) float64 {
+		switch key {
+		case "cpu":
+			if r.cpu != nil {
+				return float64(r.cpu.MilliValue())
+			}
+		case "memory":
+			if r.memory != nil {
+				return float64(r.memory.Value())
+			}
+		case "cpu%":
+			return percentOf(r.cpu, r.cpuLimit)
+		case "memory%":
+			return percentOf(r.memory, r.memLimit)
+		}
+		return -1
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if key == "handle" {
+			return rows[i].handle < rows[j].handle
+		}
+		vi, vj := value(rows[i]), value(rows[j])
+		if vi != vj {
+			return vi > vj
+		}
+		return rows[i].handle < rows[j].handle
+	})
+}
+
+func printTop(w io.Writer, rows []topRow) {
+	if len(rows) == 0 {
+		fmt.Fprintln(w, "no sandboxes found")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "HANDLE\tPOD\tCPU\tCPU REQ\tCPU LIM\tCPU%\tMEMORY\tMEM REQ\tMEM LIM\tMEM%")
+	for _, r := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.handle, r.pod,
+			formatCPU(r.cpu), formatCPU(r.cpuRequest), formatCPU(r.cpuLimit), formatPercent(percentOf(r.cpu, r.cpuLimit)),
+			formatMemory(r.memory), formatMemory(r.memRequest), formatMemory(r.memLimit), formatPercent(percentOf(r.memory, r.memLimit)),
+		)
+	}
+	tw.Flush()
+}
+
+func formatCPU(q *resource.Quantity) string {
+	if q == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%dm", q.MilliValue())
+}
+
+func formatMemory(q *resource.Quantity) string {
+	if q == nil {
+		return "-"
+	}
+	return formatBytes(q.Value())
+}
+
+func formatPercent(p float64) string {
+	if p < 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", p)
+}
+
+// watchTop refreshes the usage table every interval until ctx is done. On a
+// terminal the table is redrawn in place; otherwise each refresh is printed
+// after a timestamp line.
+func watchTop(ctx context.Context, client *kube.Client, namespace string, handles []string, sortBy string, interval time.Duration) error {
+	live := isTerminal(os.Stdout)
+	screen := &liveScreen{out: os.Stdout}
+
+	for {
+		rows, err := collectTop(ctx, client, namespace, handles)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if errors.Is(err, errNoMetrics) {
+			return err
+		}
+
+		var buf bytes.Buffer
+		if err != nil {
+			// Metrics may be briefly unavailable, e.g. while metrics-server
+			// restarts; keep trying.
+			fmt.Fprintf(&buf, "[warn] %v\n", err)
+		} else {
+			sortTop(rows, sortBy)
+			printTop(&buf, rows)
+		}
+
+		if live {
+			screen.render(buf.Bytes())
+		} else {
+			fmt.Printf("%s\n", time.Now().UTC().Format(time.RFC3339))
+			os.Stdout.Write(buf.Bytes())
+			fmt.Println()
+		}
+
+		if !sleepCtx(ctx, interval) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rathi/agentikube/internal/kube"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const testNamespace = "sandboxes"
+
+// newFakeDynamic returns a fake dynamic client serving SandboxClaims and
+// metrics.k8s.io pod metrics.
+func newFakeDynamic(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		sandboxClaimGVR: "SandboxClaimList",
+		podMetricsGVR:   "PodMetricsList",
+	}, objects...)
+}
+
+// addPodMetrics stores metrics under podMetricsGVR. The fake tracker would
+// otherwise guess "podmetricses" from the kind.
+func addPodMetrics(t *testing.T, dyn *dynamicfake.FakeDynamicClient, metrics ...*unstructured.Unstructured) {
+	t.Helper()
+	for _, m := range metrics {
+		if err := dyn.Tracker().Create(podMetricsGVR, m, testNamespace); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func testClaim(handle, pod string, paused bool) *unstructured.Unstructured {
+	claim := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "extensions.agents.x-k8s.io/v1alpha1",
+		"kind":       "SandboxClaim",
+		"metadata": map[string]interface{}{
+			"name":        sandboxName(handle),
+			"namespace":   testNamespace,
+			"annotations": map[string]interface{}{handleAnnotation: handle},
+		},
+	}}
+	if pod != "" {
+		claim.Object["status"] = map[string]interface{}{"podName": pod}
+	}
+	if paused {
+		claim.SetAnnotations(map[string]string{handleAnnotation: handle, pausedAnnotation: "2026-01-01T00:00:00Z"})
+	}
+	return claim
+}
+
+// testPodMetrics returns a PodMetrics object with one container per usage
+// pair of CPU and memory.
+func testPodMetrics(pod string, usage ...[2]string) *unstructured.Unstructured {
+	var containers []interface{}
+	for i, u := range usage {
+		containers = append(containers, map[string]interface{}{
+			"name":  "c" + string(rune('0'+i)),
+			"usage": map[string]interface{}{"cpu": u[0], "memory": u[1]},
+		})
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "metrics.k8s.io/v1beta1",
+		"kind":       "PodMetrics",
+		"metadata":   map[string]interface{}{"name": pod, "namespace": testNamespace},
+		"containers": containers,
+	}}
+}
+
+func testPod(name, cpuReq, cpuLim, memReq, memLim string) *corev1.Pod {
+	resources := corev1.ResourceRequirements{Requests: corev1.ResourceList{}, Limits: corev1.ResourceList{}}
+	set := func(list corev1.ResourceList, name corev1.ResourceName, v string) {
+		if v != "" {
+			list[name] = resource.MustParse(v)
+		}
+	}
+	set(resources.Requests, corev1.ResourceCPU, cpuReq)
+	set(resources.Limits, corev1.ResourceCPU, cpuLim)
+	set(resources.Requests, corev1.ResourceMemory, memReq)
+	set(resources.Limits, corev1.ResourceMemory, memLim)
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "sandbox", Resources: resources}}},
+	}
+}
+
+func failMetricsList(dyn *dynamicfake.FakeDynamicClient, err error) {
+	dyn.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, err
+	})
+}
+
+func TestFetchPodMetricsNotFound(t *testing.T) {
+	dyn := newFakeDynamic()
+	failMetricsList(dyn, apierrors.NewNotFound(podMetricsGVR.GroupResource(), ""))
+
+	_, err := fetchPodMetrics(context.Background(), dyn, testNamespace)
+	if !errors.Is(err, errNoMetrics) {
+		t.Fatalf("err = %v, want errNoMetrics", err)
+	}
+}
+
+func TestFetchPodMetricsServiceUnavailable(t *testing.T) {
+	dyn := newFakeDynamic()
+	failMetricsList(dyn, apierrors.NewServiceUnavailable("the server is currently unable to handle the request"))
+
+	_, err := fetchPodMetrics(context.Background(), dyn, testNamespace)
+	if err == nil || errors.Is(err, errNoMetrics) {
+		t.Fatalf("err = %v, want a not-serving-yet error", err)
+	}
+	if !strings.Contains(err.Error(), "not serving yet") {
+		t.Fatalf("err = %q, want it to say metrics-server is not serving yet", err)
+	}
+}
+
+func TestFetchPodMetricsSumsContainers(t *testing.T) {
+	dyn := newFakeDynamic()
+	addPodMetrics(t, dyn, testPodMetrics("pod-a", [2]string{"100m", "100Mi"}, [2]string{"250m", "50Mi"}))
+
+	usage, err := fetchPodMetrics(context.Background(), dyn, testNamespace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, ok := usage["pod-a"]
+	if !ok {
+		t.Fatalf("no usage for pod-a in %v", usage)
+	}
+	if got := u.cpu.MilliValue(); got != 350 {
+		t.Errorf("cpu = %dm, want 350m", got)
+	}
+	if got, want := u.memory.Value(), int64(150<<20); got != want {
+		t.Errorf("memory = %d, want %d", got, want)
+	}
+}
+
+func TestPercentOf(t *testing.T) {
+	q := func(s string) *resource.Quantity {
+		v := resource.MustParse(s)
+		return &v
+	}
+	tests := []struct {
+		name         string
+		usage, limit *resource.Quantity
+		want         float64
+	}{
+		{"half", q("500m"), q("1"), 50},
+		{"over limit", q("3"), q("2"), 150},
+		{"no limit", q("500m"), nil, -1},
+		{"zero limit", q("500m"), q("0"), -1},
+		{"no usage", nil, q("1"), -1},
+	}
+	for _, tt := range tests {
+		if got := percentOf(tt.usage, tt.limit); got != tt.want {
+			t.Errorf("%s: percentOf = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCollectTop(t *testing.T) {
+	dyn := newFakeDynamic(
+		testClaim("busy", "pod-busy", false),
+		testClaim("paused", "pod-paused", true),
+		testClaim("starting", "", false),
+	)
+	addPodMetrics(t, dyn,
+		testPodMetrics("pod-busy", [2]string{"1500m", "1Gi"}),
+		testPodMetrics("pod-paused", [2]string{"10m", "10Mi"}),
+	)
+	clientset := fake.NewSimpleClientset(
+		testPod("pod-busy", "500m", "2", "512Mi", "4Gi"),
+		testPod("pod-paused", "500m", "2", "512Mi", "4Gi"),
+	)
+	client := kube.NewClientFrom(dyn, clientset)
+
+	rows, err := collectTop(context.Background(), client, testNamespace, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byHandle := map[string]topRow{}
+	for _, r := range rows {
+		byHandle[r.handle] = r
+	}
+	if len(byHandle) != 3 {
+		t.Fatalf("got rows for %v, want busy, paused and starting", byHandle)
+	}
+
+	busy := byHandle["busy"]
+	if busy.pod != "pod-busy" || busy.cpu == nil || busy.cpu.MilliValue() != 1500 {
+		t.Errorf("busy = %+v, want pod-busy using 1500m", busy)
+	}
+	if got := percentOf(busy.cpu, busy.cpuLimit); got != 75 {
+		t.Errorf("busy cpu%% = %v, want 75", got)
+	}
+	if got := percentOf(busy.memory, busy.memLimit); got != 25 {
+		t.Errorf("busy memory%% = %v, want 25", got)
+	}
+
+	for _, handle := range []string{"paused", "starting"} {
+		r := byHandle[handle]
+		if r.pod != "-" || r.cpu != nil || r.memory != nil || r.cpuLimit != nil {
+			t.Errorf("%s = %+v, want no pod and no usage", handle, r)
+		}
+	}
+
+	if _, err := collectTop(context.Background(), client, testNamespace, []string{"missing"}); err == nil {
+		t.Error("collectTop with an unknown handle succeeded, want an error")
+	}
+}
+
+func TestSortTop(t *testing.T) {
+	q := func(s string) *resource.Quantity {
+		v := resource.MustParse(s)
+		return &v
+	}
+	rows := []topRow{
+		{handle: "a", cpu: q("100m"), cpuLimit: q("1"), memory: q("3Gi"), memLimit: q("4Gi")},
+		{handle: "b", cpu: q("900m"), cpuLimit: q("4"), memory: q("1Gi"), memLimit: q("8Gi")},
+		{handle: "c", cpu: q("500m"), cpuLimit: q("1"), memory: q("2Gi")},
+		{handle: "d"},
+	}
+	tests := []struct {
+		key  string
+		want []string
+	}{
+		{"cpu", []string{"b", "c", "a", "d"}},
+		{"memory", []string{"a", "c", "b", "d"}},
+		{"cpu%", []string{"c", "b", "a", "d"}},
+		{"memory%", []string{"a", "b", "c", "d"}},
+		{"handle", []string{"a", "b", "c", "d"}},
+	}
+	for _, tt := range tests {
+		sorted := slices.Clone(rows)
+		slices.Reverse(sorted)
+		sortTop(sorted, tt.key)
+
+		var got []string
+		for _, r := range sorted {
+			got = append(got, r.handle)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("sort by %s = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+	if len(tests) != len(topSortKeys) {
+		t.Errorf("tested %d sort keys, topSortKeys has %d", len(tests), len(topSortKeys))
+	}
+}
@@ -3,6 +3,7 @@ package commands
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/rathi/agentikube/internal/kube"
 	"github.com/rathi/agentikube/internal/manifest"
@@ -11,11 +12,14 @@ import (
 
 func NewUpCmd() *cobra.Command {
 	var dryRun bool
+	var diff bool
+	var timeout time.Duration
 
 	cmd := &cobra.Command{
 		Use:   "up",
 		Short: "Apply sandbox infrastructure to the cluster",
-		Long:  "Generates and applies all sandbox manifests (templates, warm pool, storage, compute).",
+		Long: "Generates and applies all sandbox manifests (templates, warm pool, storage, compute).\n" +
+			"With --diff nothing is applied; the changes are shown as by `agentikube plan`.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := context.Background()
 
@@ -24,6 +28,10 @@ func NewUpCmd() *cobra.Command {
 				return err
 			}
 
+			if diff {
+				return runPlan(ctx, cfg)
+			}
+
 			manifests, err := manifest.Generate(cfg)
 			if err != nil {
 				return fmt.Errorf("generating manifests: %w", err)
@@ -46,7 +54,9 @@ func NewUpCmd() *cobra.Command {
 
 			if cfg.Sandbox.WarmPool.Enabled {
 				fmt.Println("waiting for warm pool to become ready...")
-				if err := client.WaitForReady(ctx, cfg.Namespace, sandboxWarmPoolGVR, "sandbox-warm-pool"); err != nil {
+				waitCtx, cancel := context.WithTimeout(ctx, timeout)
+				defer cancel()
+				if err := client.WaitForCondition(waitCtx, cfg.Namespace, sandboxWarmPoolGVR, warmPoolName, kube.ConditionReady); err != nil {
 					return fmt.Errorf("waiting for warm pool: %w", err)
 				}
 				fmt.Println("[ok] warm pool ready")
@@ -58,6 +68,9 @@ func NewUpCmd() *cobra.Command {
 	}
 
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print manifests to stdout without applying")
+	cmd.Flags().BoolVar(&diff, "diff", false, "show what would change in the cluster without applying (exits 2 if anything would)")
+	cmd.MarkFlagsMutuallyExclusive("dry-run", "diff")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Minute, "how long to wait for the warm pool to become ready")
 
 	return cmd
 }
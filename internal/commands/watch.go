@@ -0,0 +1,310 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rathi/agentikube/internal/kube"
+	"github.com/rathi/agentikube/internal/output"
+	"golang.org/x/term"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// watchSettle coalesces bursts of events, such as a batch of creates,
+	// into a single redraw.
+	watchSettle = 200 * time.Millisecond
+
+	// watchRefresh redraws a live table even without events so the AGE
+	// column keeps moving.
+	watchRefresh = 5 * time.Second
+)
+
+// sandboxWatch keeps informer caches of the SandboxClaims and pods in a
+// namespace and signals on changed whenever either of them changes.
+type sandboxWatch struct {
+	client  *kube.Client
+	claims  cache.SharedIndexInformer
+	pods    cache.SharedIndexInformer
+	nodes   map[string]*corev1.Node
+	changed chan struct{}
+}
+
+// startSandboxWatch starts the informers and waits for their initial list.
+// A non-empty selector restricts the SandboxClaims that are watched.
+func startSandboxWatch(ctx context.Context, client *kube.Client, namespace, selector string) (*sandboxWatch, error) {
+	dyn := dynamicinformer.NewFilteredDynamicSharedInformerFactory(client.Dynamic(), 0, namespace, func(opts *metav1.ListOptions) {
+		opts.LabelSelector = selector
+	})
+	core := informers.NewSharedInformerFactoryWithOptions(client.Clientset(), 0, informers.WithNamespace(namespace))
+
+	sw := &sandboxWatch{
+		client:  client,
+		claims:  dyn.ForResource(sandboxClaimGVR).Informer(),
+		pods:    core.Core().V1().Pods().Informer(),
+		nodes:   map[string]*corev1.Node{},
+		changed: make(chan struct{}, 1),
+	}
+
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { sw.notify() },
+		UpdateFunc: func(interface{}, interface{}) { sw.notify() },
+		DeleteFunc: func(interface{}) { sw.notify() },
+	}
+	if _, err := sw.claims.AddEventHandler(handler); err != nil {
+		return nil, err
+	}
+	if _, err := sw.pods.AddEventHandler(handler); err != nil {
+		return nil, err
+	}
+
+	dyn.Start(ctx.Done())
+	core.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), sw.claims.HasSynced, sw.pods.HasSynced) {
+		return nil, fmt.Errorf("waiting for SandboxClaims and pods: %w", ctx.Err())
+	}
+	return sw, nil
+}
+
+func (sw *sandboxWatch) notify() {
+	select {
+	case sw.changed <- struct{}{}:
+	default:
+	}
+}
+
+// next blocks until the caches change, refresh elapses (if non-zero) or ctx
+// is done. It reports false once ctx is done.
+func (sw *sandboxWatch) next(ctx context.Context, refresh time.Duration) bool {
+	var tick <-chan time.Time
+	if refresh > 0 {
+		timer := time.NewTimer(refresh)
+		defer timer.Stop()
+		tick = timer.C
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-tick:
+		return true
+	case <-sw.changed:
+	}
+
+	if !sleepCtx(ctx, watchSettle) {
+		return false
+	}
+	select {
+	case <-sw.changed:
+	default:
+	}
+	return true
+}
+
+// sandboxes returns the cached sandboxes sorted by handle. With withNodes,
+// node details are looked up for newly seen nodes.
+func (sw *sandboxWatch) sandboxes(ctx context.Context, withNodes bool) []output.Sandbox {
+	p := &placement{pods: map[string]*corev1.Pod{}, nodes: sw.nodes}
+	for _, obj := range sw.pods.GetStore().List() {
+		if pod, ok := obj.(*corev1.Pod); ok {
+			p.pods[pod.Name] = pod
+		}
+	}
+
+	var items []output.Sandbox
+	for _, obj := range sw.claims.GetStore().List() {
+		claim, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		if withNodes {
+			sw.lookupNode(ctx, p, claim)
+		}
+		items = append(items, sandboxResult(claim, p))
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].Handle < items[j].Handle })
+	return items
+}
+
+// lookupNode fetches the node of the claim's pod once. Nodes that cannot be
+// read are remembered as nil so they are not requested on every redraw.
+func (sw *sandboxWatch) lookupNode(ctx context.Context, p *placement, claim *unstructured.Unstructured) {
+	pod, ok := p.pods[extractPodName(claim.Object)]
+	if !ok || pod.Spec.NodeName == "" {
+		return
+	}
+	if _, seen := sw.nodes[pod.Spec.NodeName]; seen {
+		return
+	}
+	node, err := sw.client.Clientset().CoreV1().Nodes().Get(ctx, pod.Spec.NodeName, metav1.GetOptions{})
+	if err != nil {
+		sw.nodes[pod.Spec.NodeName] = nil
+		return
+	}
+	sw.nodes[pod.Spec.NodeName] = node
+}
+
+// watchSandboxes keeps printing the sandboxes in namespace until ctx is done.
+// On a terminal the table is redrawn in place; otherwise every change is
+// printed as one line.
+func watchSandboxes(ctx context.Context, client *kube.Client, namespace, selector, format string, labelColumns []string) error {
+	sw, err := startSandboxWatch(ctx, client, namespace, selector)
+	if err != nil {
+		return err
+	}
+
+	live := isTerminal(os.Stdout)
+	screen := &liveScreen{out: os.Stdout}
+	var seen map[string]output.Sandbox
+
+	for {
+		items := sw.sandboxes(ctx, format == output.Wide)
+		if live {
+			var buf bytes.Buffer
+			printSandboxes(&buf, format, items, labelColumns)
+			screen.render(buf.Bytes())
+		} else {
+			seen = printSandboxEvents(os.Stdout, format, labelColumns, seen, items)
+		}
+
+		refresh := time.Duration(0)
+		if live {
+			refresh = watchRefresh
+		}
+		if !sw.next(ctx, refresh) {
+			return nil
+		}
+	}
+}
+
+// printSandboxEvents prints one line for every sandbox that was added,
+// changed or deleted since seen, and returns the new state.
+func printSandboxEvents(w io.Writer, format string, labelColumns []string, seen map[string]output.Sandbox, items []output.Sandbox) map[string]output.Sandbox {
+	now := time.Now().UTC().Format(time.RFC3339)
+	current := make(map[string]output.Sandbox, len(items))
+
+	for _, s := range items {
+		current[s.Handle] = s
+		prev, ok := seen[s.Handle]
+		switch {
+		case !ok:
+			fmt.Fprintf(w, "%s\tADDED\t%s\n", now, sandboxEventFields(format, labelColumns, s))
+		case sandboxEventFields(format, labelColumns, prev) != sandboxEventFields(format, labelColumns, s):
+			fmt.Fprintf(w, "%s\tMODIFIED\t%s\n", now, sandboxEventFields(format, labelColumns, s))
+		}
+	}
+
+	var deleted []string
+	for handle := range seen {
+		if _, ok := current[handle]; !ok {
+			deleted = append(deleted, handle)
+		}
+	}
+	sort.Strings(deleted)
+	for _, handle := range deleted {
+		fmt.Fprintf(w, "%s\tDELETED\t%s\n", now, sandboxEventFields(format, labelColumns, seen[handle]))
+	}
+
+	return current
+}
+
+// sandboxEventFields renders the fields of s that an event line reports.
+// Age is left out so that only real changes produce a line.
+func sandboxEventFields(format string, labelColumns []string, s output.Sandbox) string {
+	row := sandboxRow(format, s, labelColumns)
+	row = append(row[:2], row[3:]...)
+	return strings.Join(row, "\t")
+}
+
+// watchStatus keeps printing the status report until ctx is done. The report
+// is collected again whenever a SandboxClaim or pod changes, which covers
+// warm pool and node changes too since both show up as pod updates.
+func watchStatus(ctx context.Context, client *kube.Client, namespace string, karpenter bool) error {
+	sw, err := startSandboxWatch(ctx, client, namespace, "")
+	if err != nil {
+		return err
+	}
+
+	live := isTerminal(os.Stdout)
+	screen := &liveScreen{out: os.Stdout}
+	last := ""
+
+	for {
+		st := collectStatus(ctx, client, namespace, karpenter)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if live {
+			var buf bytes.Buffer
+			printStatus(&buf, st)
+			screen.render(buf.Bytes())
+		} else if line := statusLine(st); line != last {
+			fmt.Printf("%s\t%s\n", time.Now().UTC().Format(time.RFC3339), line)
+			last = line
+		}
+
+		if !sw.next(ctx, 0) {
+			return nil
+		}
+	}
+}
+
+// statusLine summarizes st on a single line for non-terminal output.
+func statusLine(st output.Status) string {
+	var parts []string
+	if st.WarmPool.Error != "" {
+		parts = append(parts, "warm-pool=error")
+	} else {
+		parts = append(parts,
+			fmt.Sprintf("warm-pool=%d/%d", st.WarmPool.Ready, st.WarmPool.Desired),
+			fmt.Sprintf("pending=%d", st.WarmPool.Pending))
+	}
+	if st.Sandboxes.Error != "" {
+		parts = append(parts, "sandboxes=error")
+	} else {
+		parts = append(parts,
+			fmt.Sprintf("sandboxes=%d", st.Sandboxes.Total),
+			fmt.Sprintf("running=%d", st.Sandboxes.Running),
+			fmt.Sprintf("paused=%d", st.Sandboxes.Paused))
+	}
+	if st.Nodes != nil {
+		if st.Nodes.Error != "" {
+			parts = append(parts, "nodes=error")
+		} else {
+			parts = append(parts, fmt.Sprintf("nodes=%d", st.Nodes.Karpenter))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// liveScreen redraws a block of text in place on a terminal.
+type liveScreen struct {
+	out   io.Writer
+	lines int
+}
+
+func (s *liveScreen) render(text []byte) {
+	if s.lines > 0 {
+		// Move to the start of the previous frame and clear to the end of
+		// the screen.
+		fmt.Fprintf(s.out, "\x1b[%dA\r\x1b[J", s.lines)
+	}
+	s.out.Write(text)
+	s.lines = bytes.Count(text, []byte("\n"))
+}
+
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
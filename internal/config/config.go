@@ -13,6 +13,7 @@ type Config struct {
 	Compute   ComputeConfig  `yaml:"compute"`
 	Storage   StorageConfig  `yaml:"storage"`
 	Sandbox   SandboxConfig  `yaml:"sandbox"`
+	Snapshots SnapshotConfig `yaml:"snapshots"`
 }
 
 type ComputeConfig struct {
@@ -48,6 +49,7 @@ type SandboxConfig struct {
 	Probes          ProbesConfig      `yaml:"probes"`
 	WarmPool        WarmPoolConfig    `yaml:"warmPool"`
 	NetworkPolicy   NetworkPolicy     `yaml:"networkPolicy"`
+	Idle            IdleConfig        `yaml:"idle"`
 }
 
 type ResourcesConfig struct {
@@ -82,6 +84,24 @@ type NetworkPolicy struct {
 	IngressPorts   []int `yaml:"ingressPorts"`
 }
 
+// IdleConfig controls when `agentikube reaper` hibernates a sandbox. A
+// sandbox is idle when no CLI session touched it, no connections are open on
+// its ports, and its average CPU stayed below CPUMillicores for the window.
+type IdleConfig struct {
+	Enabled       bool `yaml:"enabled"`
+	WindowMinutes int  `yaml:"windowMinutes"`
+	CPUMillicores int  `yaml:"cpuMillicores"`
+}
+
+// SnapshotConfig controls where workspace snapshots are stored. Location is
+// a local directory or an s3://bucket/prefix URL; Endpoint and Region apply
+// to S3-compatible stores such as MinIO.
+type SnapshotConfig struct {
+	Location string `yaml:"location"`
+	Endpoint string `yaml:"endpoint"`
+	Region   string `yaml:"region"`
+}
+
 // Load reads and parses the config file at the given path.
 func Load(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
@@ -91,6 +91,29 @@ func Validate(cfg *Config) error {
 		cfg.Sandbox.Probes.StartupFailureThreshold = 30
 	}
 
+	// Idle defaults
+	if cfg.Sandbox.Idle.WindowMinutes == 0 {
+		cfg.Sandbox.Idle.WindowMinutes = 30
+	} else if cfg.Sandbox.Idle.WindowMinutes < 0 {
+		errs = append(errs, "sandbox.idle.windowMinutes must be > 0")
+	}
+	if cfg.Sandbox.Idle.CPUMillicores == 0 {
+		cfg.Sandbox.Idle.CPUMillicores = 50
+	} else if cfg.Sandbox.Idle.CPUMillicores < 0 {
+		errs = append(errs, "sandbox.idle.cpuMillicores must be > 0")
+	}
+
+	// Snapshot defaults
+	if cfg.Snapshots.Location == "" {
+		cfg.Snapshots.Location = "~/.agentikube/snapshots"
+	}
+	if cfg.Snapshots.Region == "" {
+		cfg.Snapshots.Region = "us-east-1"
+	}
+	if cfg.Snapshots.Endpoint == "" && strings.HasPrefix(cfg.Snapshots.Location, "s3://") {
+		cfg.Snapshots.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", cfg.Snapshots.Region)
+	}
+
 	if len(errs) > 0 {
 		return fmt.Errorf("config validation errors:\n  - %s", strings.Join(errs, "\n  - "))
 	}
@@ -8,35 +8,75 @@ import (
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	yamlserializer "k8s.io/apimachinery/pkg/runtime/serializer/yaml"
 	"k8s.io/apimachinery/pkg/types"
 	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/restmapper"
-	yamlserializer "k8s.io/apimachinery/pkg/runtime/serializer/yaml"
 )
 
+// fieldManager is the server-side apply field manager agentikube uses.
+const fieldManager = "agentikube"
+
+// manifestObject is one resource decoded from a multi-document manifest.
+type manifestObject struct {
+	obj  *unstructured.Unstructured
+	gvk  schema.GroupVersionKind
+	gvr  schema.GroupVersionResource
+	json []byte
+}
+
 // ServerSideApply splits a multi-document YAML into individual resources
 // and applies each one using server-side apply with the "agentikube" field manager.
 func (c *Client) ServerSideApply(ctx context.Context, manifests []byte) error {
+	objects, err := c.decodeManifests(manifests)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range objects {
+		applyOpts := metav1.ApplyOptions{
+			FieldManager: fieldManager,
+		}
+
+		_, err := c.resource(m).Patch(
+			ctx, m.obj.GetName(), types.ApplyPatchType, m.json, applyOpts.ToPatchOptions(),
+		)
+		if err != nil {
+			return fmt.Errorf("applying %s/%s: %w", m.gvk.Kind, m.obj.GetName(), err)
+		}
+
+		fmt.Printf("applied %s/%s\n", m.gvk.Kind, m.obj.GetName())
+	}
+
+	return nil
+}
+
+// decodeManifests splits a multi-document YAML into individual resources and
+// maps each one to its GroupVersionResource.
+func (c *Client) decodeManifests(manifests []byte) ([]manifestObject, error) {
 	decoder := k8syaml.NewYAMLOrJSONDecoder(bytes.NewReader(manifests), 4096)
 
 	discoveryClient, ok := c.Clientset().Discovery().(*discovery.DiscoveryClient)
 	if !ok {
-		return fmt.Errorf("failed to get discovery client")
+		return nil, fmt.Errorf("failed to get discovery client")
 	}
 	cachedDiscovery := memory.NewMemCacheClient(discoveryClient)
 	mapper := restmapper.NewDeferredDiscoveryRESTMapper(cachedDiscovery)
 
 	deserializer := yamlserializer.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
 
+	var objects []manifestObject
 	for {
 		var rawObj unstructured.Unstructured
 		if err := decoder.Decode(&rawObj); err != nil {
 			if err == io.EOF {
 				break
 			}
-			return fmt.Errorf("decoding YAML document: %w", err)
+			return nil, fmt.Errorf("decoding YAML document: %w", err)
 		}
 
 		// Skip empty documents
@@ -47,46 +87,32 @@ func (c *Client) ServerSideApply(ctx context.Context, manifests []byte) error {
 		// Re-encode to JSON for the patch body
 		rawJSON, err := rawObj.MarshalJSON()
 		if err != nil {
-			return fmt.Errorf("marshaling to JSON: %w", err)
+			return nil, fmt.Errorf("marshaling to JSON: %w", err)
 		}
 
 		// Decode to get the GVK
 		obj := &unstructured.Unstructured{}
 		_, gvk, err := deserializer.Decode(rawJSON, nil, obj)
 		if err != nil {
-			return fmt.Errorf("deserializing object: %w", err)
+			return nil, fmt.Errorf("deserializing object: %w", err)
 		}
 
 		// Map GVK to GVR using the REST mapper
 		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
 		if err != nil {
-			return fmt.Errorf("mapping GVK %s to GVR: %w", gvk.String(), err)
+			return nil, fmt.Errorf("mapping GVK %s to GVR: %w", gvk.String(), err)
 		}
 
-		gvr := mapping.Resource
-		name := obj.GetName()
-		namespace := obj.GetNamespace()
-
-		applyOpts := metav1.ApplyOptions{
-			FieldManager: "agentikube",
-		}
+		objects = append(objects, manifestObject{obj: obj, gvk: *gvk, gvr: mapping.Resource, json: rawJSON})
+	}
 
-		// Apply using the dynamic client - handle namespaced vs cluster-scoped
-		if namespace != "" {
-			_, err = c.Dynamic().Resource(gvr).Namespace(namespace).Patch(
-				ctx, name, types.ApplyPatchType, rawJSON, applyOpts.ToPatchOptions(),
-			)
-		} else {
-			_, err = c.Dynamic().Resource(gvr).Patch(
-				ctx, name, types.ApplyPatchType, rawJSON, applyOpts.ToPatchOptions(),
-			)
-		}
-		if err != nil {
-			return fmt.Errorf("applying %s/%s: %w", gvk.Kind, name, err)
-		}
+	return objects, nil
+}
 
-		fmt.Printf("applied %s/%s\n", gvk.Kind, name)
+// resource returns the dynamic client for m, namespaced or cluster-scoped.
+func (c *Client) resource(m manifestObject) dynamic.ResourceInterface {
+	if ns := m.obj.GetNamespace(); ns != "" {
+		return c.Dynamic().Resource(m.gvr).Namespace(ns)
 	}
-
-	return nil
+	return c.Dynamic().Resource(m.gvr)
 }
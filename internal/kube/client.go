@@ -53,6 +53,13 @@ func NewClient() (*Client, error) {
 	}, nil
 }
 
+// NewClientFrom wraps existing clients, such as the fakes used in tests. The
+// REST config is nil, so exec, port-forward and other streaming calls are
+// not available.
+func NewClientFrom(dynamicClient dynamic.Interface, clientset kubernetes.Interface) *Client {
+	return &Client{dynamic: dynamicClient, clientset: clientset}
+}
+
 // EnsureNamespace creates the namespace if it does not already exist.
 func (c *Client) EnsureNamespace(ctx context.Context, name string) error {
 	_, err := c.clientset.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
@@ -1,24 +1,110 @@
 package kube
 
 import (
-	"os"
-	"os/exec"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
 )
 
-// Exec runs kubectl exec to attach an interactive terminal to the specified
-// pod. If command is empty, it defaults to /bin/sh.
-func Exec(namespace, podName string, command []string) error {
-	if len(command) == 0 {
-		command = []string{"/bin/sh"}
+// ExecOptions describes a command to run inside a pod container.
+type ExecOptions struct {
+	Namespace string
+	Pod       string
+	Container string
+	Command   []string
+
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// TTY allocates a pseudo-terminal for the session. When set, stderr is
+	// merged into stdout by the kubelet and Stderr is ignored.
+	TTY bool
+
+	// SizeQueue reports terminal resize events. Only used when TTY is set.
+	SizeQueue remotecommand.TerminalSizeQueue
+}
+
+// ExitError is returned when the remote command exits with a non-zero status.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command terminated with exit code %d", e.Code)
+}
+
+// Exec runs a command in a pod using the exec subresource. It prefers the
+// WebSocket protocol and falls back to SPDY for older API servers. A non-zero
+// remote exit status is reported as an *ExitError.
+func (c *Client) Exec(ctx context.Context, opts ExecOptions) error {
+	if len(opts.Command) == 0 {
+		opts.Command = []string{"/bin/sh"}
 	}
 
-	args := []string{"exec", "-it", "-n", namespace, podName, "--"}
-	args = append(args, command...)
+	req := c.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(opts.Namespace).
+		Name(opts.Pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: opts.Container,
+			Command:   opts.Command,
+			Stdin:     opts.Stdin != nil,
+			Stdout:    opts.Stdout != nil,
+			Stderr:    opts.Stderr != nil && !opts.TTY,
+			TTY:       opts.TTY,
+		}, scheme.ParameterCodec)
 
-	cmd := exec.Command("kubectl", args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	executor, err := c.newExecutor(req.URL())
+	if err != nil {
+		return fmt.Errorf("creating executor for pod %s/%s: %w", opts.Namespace, opts.Pod, err)
+	}
+
+	streamOpts := remotecommand.StreamOptions{
+		Stdin:  opts.Stdin,
+		Stdout: opts.Stdout,
+		Tty:    opts.TTY,
+	}
+	if !opts.TTY {
+		streamOpts.Stderr = opts.Stderr
+	} else {
+		streamOpts.TerminalSizeQueue = opts.SizeQueue
+	}
+
+	err = executor.StreamWithContext(ctx, streamOpts)
+	if err != nil {
+		var exitErr utilexec.ExitError
+		if errors.As(err, &exitErr) && exitErr.Exited() {
+			return &ExitError{Code: exitErr.ExitStatus()}
+		}
+		return fmt.Errorf("exec in pod %s/%s: %w", opts.Namespace, opts.Pod, err)
+	}
+	return nil
+}
+
+// newExecutor builds a WebSocket executor with an SPDY fallback for the given
+// exec URL.
+func (c *Client) newExecutor(u *url.URL) (remotecommand.Executor, error) {
+	wsExec, err := remotecommand.NewWebSocketExecutor(c.restConfig, "GET", u.String())
+	if err != nil {
+		return nil, err
+	}
+
+	spdyExec, err := remotecommand.NewSPDYExecutor(c.restConfig, "POST", u)
+	if err != nil {
+		return nil, err
+	}
 
-	return cmd.Run()
+	return remotecommand.NewFallbackExecutor(wsExec, spdyExec, func(err error) bool {
+		return httpstream.IsUpgradeFailure(err) || httpstream.IsHTTPSProxyError(err)
+	})
 }
@@ -0,0 +1,162 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Plan actions.
+const (
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionUnchanged = "unchanged"
+)
+
+// PlanItem is what applying one manifest object would do.
+type PlanItem struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Action    string
+	Changes   []FieldChange
+}
+
+// FieldChange is one field that applying would add, remove or change. Old
+// is nil for added fields and New is nil for removed ones.
+type FieldChange struct {
+	Path string
+	Old  interface{}
+	New  interface{}
+}
+
+// Plan runs a server-side dry-run apply of every object in manifests and
+// compares the result with the live object. Server-populated metadata and
+// status are left out of the comparison.
+func (c *Client) Plan(ctx context.Context, manifests []byte) ([]PlanItem, error) {
+	objects, err := c.decodeManifests(manifests)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []PlanItem
+	for _, m := range objects {
+		item := PlanItem{Kind: m.gvk.Kind, Namespace: m.obj.GetNamespace(), Name: m.obj.GetName()}
+		res := c.resource(m)
+
+		live, err := res.Get(ctx, item.Name, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, fmt.Errorf("getting %s/%s: %w", item.Kind, item.Name, err)
+		}
+
+		opts := metav1.ApplyOptions{FieldManager: fieldManager, DryRun: []string{metav1.DryRunAll}}
+		planned, err := res.Patch(ctx, item.Name, types.ApplyPatchType, m.json, opts.ToPatchOptions())
+		if errors.IsNotFound(err) && live == nil {
+			// The namespace is created by the same apply, so the server
+			// cannot dry-run objects in it yet; show them as rendered.
+			planned, err = m.obj, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("dry-run applying %s/%s: %w", item.Kind, item.Name, err)
+		}
+
+		var before map[string]interface{}
+		if live != nil {
+			before = desiredState(live)
+		}
+		item.Changes = diffFields("", before, desiredState(planned))
+
+		switch {
+		case live == nil:
+			item.Action = ActionCreate
+		case len(item.Changes) > 0:
+			item.Action = ActionUpdate
+		default:
+			item.Action = ActionUnchanged
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// desiredState returns the fields of obj that describe its desired state.
+func desiredState(obj *unstructured.Unstructured) map[string]interface{} {
+	out := obj.DeepCopy()
+	for _, field := range []string{"managedFields", "resourceVersion", "generation", "uid", "creationTimestamp", "selfLink"} {
+		unstructured.RemoveNestedField(out.Object, "metadata", field)
+	}
+	unstructured.RemoveNestedField(out.Object, "status")
+	return out.Object
+}
+
+// diffFields compares two decoded JSON values and returns the leaf fields
+// that differ, in path order.
+func diffFields(path string, before, after interface{}) []FieldChange {
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+	if beforeIsMap && afterIsMap || before == nil && afterIsMap || beforeIsMap && after == nil {
+		keys := map[string]bool{}
+		for k := range beforeMap {
+			keys[k] = true
+		}
+		for k := range afterMap {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+
+		var changes []FieldChange
+		for _, k := range sorted {
+			changes = append(changes, diffFields(joinPath(path, k), beforeMap[k], afterMap[k])...)
+		}
+		return changes
+	}
+
+	beforeList, beforeIsList := before.([]interface{})
+	afterList, afterIsList := after.([]interface{})
+	if beforeIsList && afterIsList || before == nil && afterIsList || beforeIsList && after == nil {
+		var changes []FieldChange
+		for i := 0; i < max(len(beforeList), len(afterList)); i++ {
+			var b, a interface{}
+			if i < len(beforeList) {
+				b = beforeList[i]
+			}
+			if i < len(afterList) {
+				a = afterList[i]
+			}
+			changes = append(changes, diffFields(fmt.Sprintf("%s[%d]", path, i), b, a)...)
+		}
+		return changes
+	}
+
+	if reflect.DeepEqual(before, after) {
+		return nil
+	}
+	return []FieldChange{{Path: path, Old: before, New: after}}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// FormatValue renders a field value for display.
+func FormatValue(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,162 @@
+package kube
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestDiffFields(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after interface{}
+		want          []FieldChange
+	}{
+		{
+			name:   "equal",
+			before: map[string]interface{}{"a": int64(1), "b": []interface{}{"x"}},
+			after:  map[string]interface{}{"a": int64(1), "b": []interface{}{"x"}},
+		},
+		{
+			name:   "nested map value",
+			before: map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(1), "paused": false}},
+			after:  map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(3), "paused": false}},
+			want:   []FieldChange{{Path: "spec.replicas", Old: int64(1), New: int64(3)}},
+		},
+		{
+			name:   "added and removed keys",
+			before: map[string]interface{}{"a": "1", "b": "2"},
+			after:  map[string]interface{}{"b": "2", "c": "3"},
+			want: []FieldChange{
+				{Path: "a", Old: "1"},
+				{Path: "c", New: "3"},
+			},
+		},
+		{
+			name:   "added nested map",
+			before: map[string]interface{}{},
+			after:  map[string]interface{}{"labels": map[string]interface{}{"app": "x", "tier": "y"}},
+			want: []FieldChange{
+				{Path: "labels.app", New: "x"},
+				{Path: "labels.tier", New: "y"},
+			},
+		},
+		{
+			name: "list items by index",
+			before: map[string]interface{}{"containers": []interface{}{
+				map[string]interface{}{"name": "a", "image": "a:1"},
+				map[string]interface{}{"name": "b", "image": "b:1"},
+			}},
+			after: map[string]interface{}{"containers": []interface{}{
+				map[string]interface{}{"name": "a", "image": "a:2"},
+			}},
+			want: []FieldChange{
+				{Path: "containers[0].image", Old: "a:1", New: "a:2"},
+				{Path: "containers[1].image", Old: "b:1"},
+				{Path: "containers[1].name", Old: "b"},
+			},
+		},
+		{
+			name:   "list grows",
+			before: map[string]interface{}{"args": []interface{}{"--a"}},
+			after:  map[string]interface{}{"args": []interface{}{"--a", "--b"}},
+			want:   []FieldChange{{Path: "args[1]", New: "--b"}},
+		},
+		{
+			name:   "type change",
+			before: map[string]interface{}{"v": "1"},
+			after:  map[string]interface{}{"v": map[string]interface{}{"x": "1"}},
+			want:   []FieldChange{{Path: "v", Old: "1", New: map[string]interface{}{"x": "1"}}},
+		},
+		{
+			name:   "new object",
+			before: nil,
+			after:  map[string]interface{}{"kind": "ConfigMap", "data": map[string]interface{}{"k": "v"}},
+			want: []FieldChange{
+				{Path: "data.k", New: "v"},
+				{Path: "kind", New: "ConfigMap"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		got := diffFields("", tt.before, tt.after)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: diffFields =\n  %#v\nwant\n  %#v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// Fields the server fills in show up in both the live object and the
+// dry-run result, so they only differ where the manifest changed.
+func TestDiffServerDefaults(t *testing.T) {
+	live := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata": map[string]interface{}{
+			"name":              "api",
+			"namespace":         "sandboxes",
+			"uid":               "1234",
+			"resourceVersion":   "100",
+			"generation":        int64(1),
+			"creationTimestamp": "2026-01-01T00:00:00Z",
+			"managedFields":     []interface{}{map[string]interface{}{"manager": "agentikube"}},
+			"labels":            map[string]interface{}{"app": "api"},
+		},
+		"spec": map[string]interface{}{
+			"clusterIP":       "10.0.0.1",
+			"type":            "ClusterIP",
+			"sessionAffinity": "None",
+			"ports":           []interface{}{map[string]interface{}{"port": int64(80), "protocol": "TCP"}},
+		},
+		"status": map[string]interface{}{"loadBalancer": map[string]interface{}{}},
+	}}
+	planned := live.DeepCopy()
+	planned.SetResourceVersion("101")
+	planned.SetGeneration(2)
+	planned.SetManagedFields(nil)
+	unstructured.SetNestedField(planned.Object, map[string]interface{}{"ingress": []interface{}{}}, "status", "loadBalancer")
+	unstructured.SetNestedSlice(planned.Object, []interface{}{
+		map[string]interface{}{"port": int64(8080), "protocol": "TCP"},
+	}, "spec", "ports")
+
+	got := diffFields("", desiredState(live), desiredState(planned))
+	want := []FieldChange{{Path: "spec.ports[0].port", Old: int64(80), New: int64(8080)}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diff = %#v, want %#v", got, want)
+	}
+}
+
+func TestDesiredState(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"kind": "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":              "c",
+			"uid":               "1234",
+			"resourceVersion":   "100",
+			"generation":        int64(1),
+			"creationTimestamp": "2026-01-01T00:00:00Z",
+			"selfLink":          "/api/v1/configmaps/c",
+			"managedFields":     []interface{}{},
+			"annotations":       map[string]interface{}{"a": "b"},
+		},
+		"data":   map[string]interface{}{"k": "v"},
+		"status": map[string]interface{}{"phase": "x"},
+	}}
+
+	got := desiredState(obj)
+	want := map[string]interface{}{
+		"kind": "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":        "c",
+			"annotations": map[string]interface{}{"a": "b"},
+		},
+		"data": map[string]interface{}{"k": "v"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("desiredState = %#v, want %#v", got, want)
+	}
+	if _, ok := obj.Object["status"]; !ok {
+		t.Error("desiredState modified its argument")
+	}
+}
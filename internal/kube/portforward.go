@@ -0,0 +1,196 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// PortForwardOptions describes a set of ports to forward from the local
+// machine to a pod.
+type PortForwardOptions struct {
+	Namespace string
+	Pod       string
+
+	// Addresses are the local addresses to listen on. Defaults to localhost.
+	Addresses []string
+
+	// Ports use the "local:remote" (or "port") form accepted by
+	// kubectl port-forward.
+	Ports []string
+
+	// Ready, if non-nil, is closed once the local listeners are up.
+	Ready chan struct{}
+
+	Out    io.Writer
+	ErrOut io.Writer
+}
+
+// PortForward forwards local ports to a pod until ctx is cancelled or the
+// connection to the pod is lost. It returns nil when ctx is cancelled.
+func (c *Client) PortForward(ctx context.Context, opts PortForwardOptions) error {
+	if len(opts.Addresses) == 0 {
+		opts.Addresses = []string{"localhost"}
+	}
+
+	dialer, err := c.portForwardDialer(opts.Namespace, opts.Pod)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stop := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		close(stop)
+	}()
+
+	ready := opts.Ready
+	if ready == nil {
+		ready = make(chan struct{})
+	}
+
+	fw, err := portforward.NewOnAddresses(dialer, opts.Addresses, opts.Ports, stop, ready, opts.Out, opts.ErrOut)
+	if err != nil {
+		return fmt.Errorf("setting up port-forward to pod %s/%s: %w", opts.Namespace, opts.Pod, err)
+	}
+
+	if err := fw.ForwardPorts(); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("port-forward to pod %s/%s: %w", opts.Namespace, opts.Pod, err)
+	}
+	return nil
+}
+
+// DialPort opens a single stream to port in a pod through the portforward
+// subresource, without listening locally. Closing the returned connection
+// tears down the underlying API server connection.
+func (c *Client) DialPort(namespace, podName string, port int) (io.ReadWriteCloser, error) {
+	dialer, err := c.portForwardDialer(namespace, podName)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, protocol, err := dialer.Dial(portforward.PortForwardProtocolV1Name)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to pod %s/%s: %w", namespace, podName, err)
+	}
+	if protocol != portforward.PortForwardProtocolV1Name {
+		conn.Close()
+		return nil, fmt.Errorf("unable to negotiate port-forward protocol, server returned %q", protocol)
+	}
+
+	headers := http.Header{}
+	headers.Set(corev1.StreamType, corev1.StreamTypeError)
+	headers.Set(corev1.PortHeader, strconv.Itoa(port))
+	headers.Set(corev1.PortForwardRequestIDHeader, "0")
+	errorStream, err := conn.CreateStream(headers)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("creating error stream: %w", err)
+	}
+	// The error stream is read-only from our side.
+	errorStream.Close()
+
+	headers.Set(corev1.StreamType, corev1.StreamTypeData)
+	dataStream, err := conn.CreateStream(headers)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("creating data stream: %w", err)
+	}
+
+	pc := &portConn{conn: conn, data: dataStream}
+	go func() {
+		msg, err := io.ReadAll(errorStream)
+		if err == nil && len(msg) > 0 {
+			pc.setErr(fmt.Errorf("port %d in pod %s/%s: %s", port, namespace, podName, msg))
+			conn.Close()
+		}
+	}()
+	return pc, nil
+}
+
+// portConn is a port-forward data stream that reports errors sent by the
+// kubelet on the matching error stream.
+type portConn struct {
+	conn httpstream.Connection
+	data httpstream.Stream
+
+	mu  sync.Mutex
+	err error
+}
+
+func (p *portConn) Read(b []byte) (int, error) {
+	n, err := p.data.Read(b)
+	if err != nil {
+		if streamErr := p.getErr(); streamErr != nil {
+			return n, streamErr
+		}
+	}
+	return n, err
+}
+
+func (p *portConn) Write(b []byte) (int, error) {
+	n, err := p.data.Write(b)
+	if err != nil {
+		if streamErr := p.getErr(); streamErr != nil {
+			return n, streamErr
+		}
+	}
+	return n, err
+}
+
+func (p *portConn) Close() error {
+	p.data.Close()
+	return p.conn.Close()
+}
+
+func (p *portConn) setErr(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+func (p *portConn) getErr() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// portForwardDialer returns a dialer for the portforward subresource of a
+// pod. It tunnels over WebSocket and falls back to SPDY.
+func (c *Client) portForwardDialer(namespace, podName string) (httpstream.Dialer, error) {
+	u := c.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(podName).
+		SubResource("portforward").
+		URL()
+
+	transport, upgrader, err := spdy.RoundTripperFor(c.restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("creating SPDY transport: %w", err)
+	}
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", u)
+
+	tunnelingDialer, err := portforward.NewSPDYOverWebsocketDialer(u, c.restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("creating WebSocket dialer: %w", err)
+	}
+	return portforward.NewFallbackDialer(tunnelingDialer, dialer, func(err error) bool {
+		return httpstream.IsUpgradeFailure(err) || httpstream.IsHTTPSProxyError(err)
+	}), nil
+}
@@ -0,0 +1,94 @@
+package kube
+
+import (
+	"context"
+	"os"
+
+	"golang.org/x/term"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// Shell attaches an interactive session to a pod container using the local
+// stdin/stdout. When stdin is a terminal it is switched to raw mode for the
+// duration of the session and window resizes are forwarded to the pod.
+func (c *Client) Shell(ctx context.Context, namespace, podName string, command []string) error {
+	opts := ExecOptions{
+		Namespace: namespace,
+		Pod:       podName,
+		Command:   command,
+		Stdin:     os.Stdin,
+		Stdout:    os.Stdout,
+		Stderr:    os.Stderr,
+	}
+
+	inFd := int(os.Stdin.Fd())
+	if term.IsTerminal(inFd) {
+		state, err := term.MakeRaw(inFd)
+		if err != nil {
+			return err
+		}
+		defer term.Restore(inFd, state)
+
+		queue := newSizeQueue(int(os.Stdout.Fd()))
+		defer queue.stop()
+
+		opts.TTY = true
+		opts.SizeQueue = queue
+	}
+
+	return c.Exec(ctx, opts)
+}
+
+// sizeQueue implements remotecommand.TerminalSizeQueue for a local terminal.
+type sizeQueue struct {
+	fd     int
+	last   remotecommand.TerminalSize
+	resize chan remotecommand.TerminalSize
+	done   chan struct{}
+}
+
+func newSizeQueue(fd int) *sizeQueue {
+	q := &sizeQueue{
+		fd:     fd,
+		resize: make(chan remotecommand.TerminalSize, 1),
+		done:   make(chan struct{}),
+	}
+	q.update()
+	go q.watch()
+	return q
+}
+
+// Next blocks until the terminal is resized and returns the new size, or
+// returns nil once the queue has been stopped.
+func (q *sizeQueue) Next() *remotecommand.TerminalSize {
+	select {
+	case size := <-q.resize:
+		return &size
+	case <-q.done:
+		return nil
+	}
+}
+
+func (q *sizeQueue) stop() {
+	close(q.done)
+}
+
+// update reads the current terminal size and queues it if it changed,
+// replacing any size that has not been consumed yet.
+func (q *sizeQueue) update() {
+	width, height, err := term.GetSize(q.fd)
+	if err != nil {
+		return
+	}
+	size := remotecommand.TerminalSize{Width: uint16(width), Height: uint16(height)}
+	if size == q.last {
+		return
+	}
+	q.last = size
+
+	select {
+	case <-q.resize:
+	default:
+	}
+	q.resize <- size
+}
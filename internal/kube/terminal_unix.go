@@ -0,0 +1,25 @@
+//go:build !windows
+
+package kube
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watch pushes a new size on every SIGWINCH until the queue is stopped.
+func (q *sizeQueue) watch() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGWINCH)
+	defer signal.Stop(sigs)
+
+	for {
+		select {
+		case <-sigs:
+			q.update()
+		case <-q.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,20 @@
+//go:build windows
+
+package kube
+
+import "time"
+
+// watch polls the console size since Windows has no SIGWINCH.
+func (q *sizeQueue) watch() {
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			q.update()
+		case <-q.done:
+			return
+		}
+	}
+}
@@ -3,72 +3,228 @@ package kube
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/watch"
 )
 
+// Condition is a status condition to wait for, e.g. Ready=True.
+type Condition struct {
+	Type   string
+	Status string
+}
+
+// ConditionReady is the condition WaitForReady waits for.
+var ConditionReady = Condition{Type: "Ready", Status: "True"}
+
+func (c Condition) String() string {
+	return c.Type + "=" + c.Status
+}
+
+// Backoff bounds for retrying failed list and watch calls.
+const (
+	waitBackoffMin = 500 * time.Millisecond
+	waitBackoffMax = 30 * time.Second
+)
+
 // WaitForReady watches a resource until its Ready condition becomes True
 // or the context is cancelled/times out.
 func (c *Client) WaitForReady(ctx context.Context, namespace string, gvr schema.GroupVersionResource, name string) error {
-	watcher, err := c.Dynamic().Resource(gvr).Namespace(namespace).Watch(ctx, metav1.ListOptions{
-		FieldSelector: fmt.Sprintf("metadata.name=%s", name),
-	})
-	if err != nil {
-		return fmt.Errorf("watching %s %s/%s: %w", gvr.Resource, namespace, name, err)
+	return c.WaitForCondition(ctx, namespace, gvr, name, ConditionReady)
+}
+
+// WaitForCondition waits until the named resource has a status condition
+// matching cond, or the context is done. It lists first, so a resource that
+// already satisfies cond returns immediately, then watches from the listed
+// resourceVersion. Closed watches are resumed from the last version seen,
+// an expired version (410 Gone) triggers a fresh list, and transient API
+// errors are retried with exponential backoff. It fails if the resource is
+// deleted while waiting.
+func (c *Client) WaitForCondition(ctx context.Context, namespace string, gvr schema.GroupVersionResource, name string, cond Condition) error {
+	res := c.Dynamic().Resource(gvr).Namespace(namespace)
+	selector := fields.OneTermEqualSelector("metadata.name", name).String()
+
+	// last describes the condition as last observed, for the timeout error.
+	last := "not observed yet"
+	timeout := func() error {
+		return fmt.Errorf("timed out waiting for %s %s/%s to have condition %s (last: %s)", gvr.Resource, namespace, name, cond, last)
+	}
+
+	resourceVersion := ""
+	delay := waitBackoffMin
+	retry := func(err error) error {
+		if !isTransient(err) {
+			return fmt.Errorf("waiting for %s %s/%s: %w", gvr.Resource, namespace, name, err)
+		}
+		t := time.NewTimer(delay)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return timeout()
+		case <-t.C:
+		}
+		delay = min(delay*2, waitBackoffMax)
+		return nil
 	}
-	defer watcher.Stop()
 
+	for {
+		if ctx.Err() != nil {
+			return timeout()
+		}
+
+		if resourceVersion == "" {
+			list, err := res.List(ctx, metav1.ListOptions{FieldSelector: selector})
+			if err != nil {
+				if ctx.Err() != nil {
+					return timeout()
+				}
+				if err := retry(err); err != nil {
+					return err
+				}
+				continue
+			}
+			for i := range list.Items {
+				met, state := hasCondition(&list.Items[i], cond)
+				if met {
+					return nil
+				}
+				last = state
+			}
+			resourceVersion = list.GetResourceVersion()
+			delay = waitBackoffMin
+		}
+
+		watcher, err := res.Watch(ctx, metav1.ListOptions{
+			FieldSelector:       selector,
+			ResourceVersion:     resourceVersion,
+			AllowWatchBookmarks: true,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return timeout()
+			}
+			if errors.IsGone(err) || errors.IsResourceExpired(err) {
+				resourceVersion = ""
+				continue
+			}
+			if err := retry(err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		done, err := c.consumeWatch(ctx, watcher, cond, &resourceVersion, &last)
+		watcher.Stop()
+		switch {
+		case done:
+			return nil
+		case ctx.Err() != nil:
+			return timeout()
+		case err == nil:
+			// The server closed the watch; resume from the last version.
+			continue
+		case errors.IsGone(err) || errors.IsResourceExpired(err):
+			resourceVersion = ""
+		case err == errDeleted:
+			return fmt.Errorf("%s %s/%s was deleted while waiting for condition %s", gvr.Resource, namespace, name, cond)
+		default:
+			if err := retry(err); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// errDeleted is returned by consumeWatch when the watched object goes away.
+var errDeleted = fmt.Errorf("deleted")
+
+// consumeWatch reads events until the condition is met, the channel closes
+// or an error event arrives. It keeps resourceVersion and last up to date.
+func (c *Client) consumeWatch(ctx context.Context, watcher watch.Interface, cond Condition, resourceVersion, last *string) (bool, error) {
 	for {
 		select {
 		case <-ctx.Done():
-			return fmt.Errorf("timed out waiting for %s %s/%s to become ready", gvr.Resource, namespace, name)
+			return false, ctx.Err()
 		case event, ok := <-watcher.ResultChan():
 			if !ok {
-				return fmt.Errorf("watch channel closed for %s %s/%s", gvr.Resource, namespace, name)
+				return false, nil
 			}
 			if event.Type == watch.Error {
-				return fmt.Errorf("watch error for %s %s/%s", gvr.Resource, namespace, name)
+				return false, errors.FromObject(event.Object)
 			}
 
 			obj, ok := event.Object.(*unstructured.Unstructured)
 			if !ok {
 				continue
 			}
+			*resourceVersion = obj.GetResourceVersion()
 
-			if isReady(obj) {
-				return nil
+			switch event.Type {
+			case watch.Deleted:
+				return false, errDeleted
+			case watch.Added, watch.Modified:
+				met, state := hasCondition(obj, cond)
+				if met {
+					return true, nil
+				}
+				*last = state
 			}
 		}
 	}
 }
 
-// isReady checks whether an unstructured object has a condition with
-// type=Ready and status=True.
-func isReady(obj *unstructured.Unstructured) bool {
-	status, found, err := unstructured.NestedMap(obj.Object, "status")
-	if err != nil || !found {
+// isTransient reports whether a failed list or watch is worth retrying.
+// Errors that will not go away by themselves, such as a missing resource
+// type or missing permissions, are returned to the caller.
+func isTransient(err error) bool {
+	switch {
+	case errors.IsNotFound(err),
+		errors.IsForbidden(err),
+		errors.IsUnauthorized(err),
+		errors.IsBadRequest(err),
+		errors.IsInvalid(err),
+		errors.IsMethodNotSupported(err):
 		return false
+	default:
+		return true
 	}
+}
 
-	conditionsRaw, found, err := unstructured.NestedSlice(status, "conditions")
+// hasCondition reports whether obj has a condition matching cond. It also
+// describes the current state of that condition for error messages.
+func hasCondition(obj *unstructured.Unstructured, cond Condition) (bool, string) {
+	conditions, found, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
 	if err != nil || !found {
-		return false
+		return false, "no conditions reported"
 	}
 
-	for _, c := range conditionsRaw {
+	for _, c := range conditions {
 		condition, ok := c.(map[string]interface{})
 		if !ok {
 			continue
 		}
 		condType, _ := condition["type"].(string)
+		if condType != cond.Type {
+			continue
+		}
 		condStatus, _ := condition["status"].(string)
-		if condType == "Ready" && condStatus == "True" {
-			return true
+		if condStatus == cond.Status {
+			return true, ""
 		}
-	}
 
-	return false
+		state := cond.Type + "=" + condStatus
+		if reason, _ := condition["reason"].(string); reason != "" {
+			state += " (" + reason + ")"
+		}
+		if message, _ := condition["message"].(string); message != "" {
+			state += ": " + message
+		}
+		return false, state
+	}
+	return false, "no " + cond.Type + " condition"
 }
@@ -0,0 +1,237 @@
+package kube
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var widgetGVR = schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+
+func widget(resourceVersion, ready, reason string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"metadata": map[string]interface{}{
+			"name":            "w",
+			"namespace":       "ns",
+			"resourceVersion": resourceVersion,
+		},
+	}}
+	if ready != "" {
+		obj.Object["status"] = map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": ready, "reason": reason},
+			},
+		}
+	}
+	return obj
+}
+
+// fakeAPI serves scripted list results and watches for widgets and records
+// the resourceVersion of every watch.
+type fakeAPI struct {
+	t      *testing.T
+	client *Client
+
+	mu       sync.Mutex
+	lists    []func() (runtime.Object, error)
+	watches  []func(w *watch.FakeWatcher) error
+	watchRVs []string
+	listed   int
+}
+
+func newFakeAPI(t *testing.T) *fakeAPI {
+	f := &fakeAPI{t: t}
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		widgetGVR: "WidgetList",
+	})
+	dyn.PrependReactor("list", "widgets", func(k8stesting.Action) (bool, runtime.Object, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if len(f.lists) == 0 {
+			f.t.Errorf("unexpected list #%d", f.listed+1)
+			return true, nil, apierrors.NewForbidden(widgetGVR.GroupResource(), "", nil)
+		}
+		next := f.lists[0]
+		f.lists = f.lists[1:]
+		f.listed++
+		obj, err := next()
+		return true, obj, err
+	})
+	dyn.PrependWatchReactor("widgets", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.watchRVs = append(f.watchRVs, action.(k8stesting.WatchAction).GetWatchRestrictions().ResourceVersion)
+		if len(f.watches) == 0 {
+			// Block until the test's context ends.
+			return true, watch.NewFake(), nil
+		}
+		next := f.watches[0]
+		f.watches = f.watches[1:]
+		w := watch.NewFakeWithChanSize(8, false)
+		if err := next(w); err != nil {
+			return true, nil, err
+		}
+		return true, w, nil
+	})
+	f.client = &Client{dynamic: dyn}
+	return f
+}
+
+func (f *fakeAPI) list(resourceVersion string, items ...*unstructured.Unstructured) {
+	f.lists = append(f.lists, func() (runtime.Object, error) {
+		list := &unstructured.UnstructuredList{Object: map[string]interface{}{
+			"apiVersion": "example.com/v1",
+			"kind":       "WidgetList",
+		}}
+		list.SetResourceVersion(resourceVersion)
+		for _, item := range items {
+			list.Items = append(list.Items, *item)
+		}
+		return list, nil
+	})
+}
+
+func (f *fakeAPI) listError(err error) {
+	f.lists = append(f.lists, func() (runtime.Object, error) { return nil, err })
+}
+
+func (f *fakeAPI) watch(script func(w *watch.FakeWatcher) error) {
+	f.watches = append(f.watches, script)
+}
+
+func (f *fakeAPI) wait(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return f.client.WaitForCondition(ctx, "ns", widgetGVR, "w", ConditionReady)
+}
+
+func (f *fakeAPI) assertWatches(want ...string) {
+	f.t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if strings.Join(f.watchRVs, ",") != strings.Join(want, ",") {
+		f.t.Errorf("watched from resourceVersions %q, want %q", f.watchRVs, want)
+	}
+}
+
+func TestWaitForConditionAlreadyMet(t *testing.T) {
+	f := newFakeAPI(t)
+	f.list("10", widget("10", "True", ""))
+
+	if err := f.wait(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	f.assertWatches()
+}
+
+func TestWaitForConditionLaterEvent(t *testing.T) {
+	f := newFakeAPI(t)
+	f.list("10", widget("10", "False", "Pending"))
+	f.watch(func(w *watch.FakeWatcher) error {
+		w.Modify(widget("11", "False", "Starting"))
+		w.Modify(widget("12", "True", ""))
+		return nil
+	})
+
+	if err := f.wait(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	f.assertWatches("10")
+}
+
+func TestWaitForConditionWatchClosed(t *testing.T) {
+	f := newFakeAPI(t)
+	f.list("10", widget("10", "False", "Pending"))
+	f.watch(func(w *watch.FakeWatcher) error {
+		w.Modify(widget("11", "False", "Starting"))
+		w.Stop()
+		return nil
+	})
+	f.watch(func(w *watch.FakeWatcher) error {
+		w.Modify(widget("12", "True", ""))
+		return nil
+	})
+
+	if err := f.wait(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	// The second watch resumes after the last event instead of relisting.
+	f.assertWatches("10", "11")
+	if f.listed != 1 {
+		t.Errorf("listed %d times, want 1", f.listed)
+	}
+}
+
+func TestWaitForConditionGone(t *testing.T) {
+	f := newFakeAPI(t)
+	f.list("10", widget("10", "False", "Pending"))
+	f.watch(func(w *watch.FakeWatcher) error {
+		w.Error(&metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    410,
+			Reason:  metav1.StatusReasonGone,
+			Message: "too old resource version: 10 (42)",
+		})
+		return nil
+	})
+	f.list("42", widget("42", "False", "Starting"))
+	f.watch(func(*watch.FakeWatcher) error {
+		return apierrors.NewResourceExpired("too old resource version: 42 (50)")
+	})
+	f.list("50", widget("50", "True", ""))
+
+	if err := f.wait(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	f.assertWatches("10", "42")
+	if f.listed != 3 {
+		t.Errorf("listed %d times, want 3", f.listed)
+	}
+}
+
+func TestWaitForConditionDeleted(t *testing.T) {
+	f := newFakeAPI(t)
+	f.list("10", widget("10", "False", "Pending"))
+	f.watch(func(w *watch.FakeWatcher) error {
+		w.Delete(widget("11", "False", "Pending"))
+		return nil
+	})
+
+	err := f.wait(time.Second)
+	if err == nil || !strings.Contains(err.Error(), "was deleted while waiting") {
+		t.Fatalf("err = %v, want a deleted error", err)
+	}
+}
+
+func TestWaitForConditionTimeout(t *testing.T) {
+	f := newFakeAPI(t)
+	f.list("10", widget("10", "False", "Pending"))
+
+	err := f.wait(50 * time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "timed out") || !strings.Contains(err.Error(), "last: Ready=False (Pending)") {
+		t.Fatalf("err = %v, want a timeout naming the last state", err)
+	}
+}
+
+func TestWaitForConditionPermanentError(t *testing.T) {
+	f := newFakeAPI(t)
+	f.listError(apierrors.NewForbidden(widgetGVR.GroupResource(), "", nil))
+
+	err := f.wait(time.Second)
+	if err == nil || !apierrors.IsForbidden(err) {
+		t.Fatalf("err = %v, want the Forbidden error without retrying", err)
+	}
+}
@@ -0,0 +1,137 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// APIVersion is stamped on every structured result. Fields may be added
+// within a version; renaming or removing one requires a new version.
+const APIVersion = "agentikube.io/v1alpha1"
+
+// Output formats accepted by --output. Table is the default human-readable
+// format.
+const (
+	Table = ""
+	Wide  = "wide"
+	JSON  = "json"
+	YAML  = "yaml"
+	Name  = "name"
+)
+
+// ParseFormat validates the value of --output.
+func ParseFormat(s string) (string, error) {
+	switch s {
+	case Table, Wide, JSON, YAML, Name:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown output format %q (use json, yaml, wide or name)", s)
+	}
+}
+
+// IsStructured reports whether format is a machine-readable encoding.
+func IsStructured(format string) bool {
+	return format == JSON || format == YAML
+}
+
+// Sandbox describes a single sandbox as returned by list and create.
+type Sandbox struct {
+	APIVersion string `json:"apiVersion" yaml:"apiVersion"`
+	Kind       string `json:"kind" yaml:"kind"`
+
+	Handle    string            `json:"handle" yaml:"handle"`
+	Name      string            `json:"name" yaml:"name"`
+	Namespace string            `json:"namespace" yaml:"namespace"`
+	Status    string            `json:"status" yaml:"status"`
+	Pod       string            `json:"pod,omitempty" yaml:"pod,omitempty"`
+	PodIP     string            `json:"podIP,omitempty" yaml:"podIP,omitempty"`
+	Node      string            `json:"node,omitempty" yaml:"node,omitempty"`
+	Created   time.Time         `json:"created" yaml:"created"`
+	Age       string            `json:"age" yaml:"age"`
+	Labels    map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	ReadyAfter  string `json:"readyAfter,omitempty" yaml:"readyAfter,omitempty"`
+
+	InstanceType string `json:"instanceType,omitempty" yaml:"instanceType,omitempty"`
+	CapacityType string `json:"capacityType,omitempty" yaml:"capacityType,omitempty"`
+}
+
+// SandboxList is the result of list.
+type SandboxList struct {
+	APIVersion string    `json:"apiVersion" yaml:"apiVersion"`
+	Kind       string    `json:"kind" yaml:"kind"`
+	Items      []Sandbox `json:"items" yaml:"items"`
+}
+
+// Status is the result of status. Sections that could not be read carry an
+// Error instead of failing the whole command.
+type Status struct {
+	APIVersion string         `json:"apiVersion" yaml:"apiVersion"`
+	Kind       string         `json:"kind" yaml:"kind"`
+	Namespace  string         `json:"namespace" yaml:"namespace"`
+	WarmPool   WarmPoolStatus `json:"warmPool" yaml:"warmPool"`
+	Sandboxes  SandboxCounts  `json:"sandboxes" yaml:"sandboxes"`
+	Nodes      *NodeCounts    `json:"nodes,omitempty" yaml:"nodes,omitempty"`
+}
+
+type WarmPoolStatus struct {
+	Desired int64  `json:"desired" yaml:"desired"`
+	Ready   int64  `json:"ready" yaml:"ready"`
+	Pending int64  `json:"pending" yaml:"pending"`
+	Error   string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+type SandboxCounts struct {
+	Total   int    `json:"total" yaml:"total"`
+	Running int    `json:"running" yaml:"running"`
+	Paused  int    `json:"paused" yaml:"paused"`
+	Error   string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// NodeCounts is only reported for karpenter compute.
+type NodeCounts struct {
+	Karpenter int    `json:"karpenter" yaml:"karpenter"`
+	Error     string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// NewSandbox returns a Sandbox with its type fields set.
+func NewSandbox() Sandbox {
+	return Sandbox{APIVersion: APIVersion, Kind: "Sandbox"}
+}
+
+// NewSandboxList returns a SandboxList with its type fields set.
+func NewSandboxList(items []Sandbox) SandboxList {
+	if items == nil {
+		items = []Sandbox{}
+	}
+	return SandboxList{APIVersion: APIVersion, Kind: "SandboxList", Items: items}
+}
+
+// NewStatus returns a Status with its type fields set.
+func NewStatus() Status {
+	return Status{APIVersion: APIVersion, Kind: "Status"}
+}
+
+// Write encodes v to w as JSON or YAML.
+func Write(w io.Writer, format string, v any) error {
+	switch format {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case YAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		return fmt.Errorf("output format %q is not a structured format", format)
+	}
+}
@@ -0,0 +1,113 @@
+// Package pricing holds the price table used to estimate what sandbox
+// compute costs. A default table for us-east-1 is embedded; users can
+// override entries with their own file.
+package pricing
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed prices.yaml
+var defaultPrices []byte
+
+// Capacity types as used by the karpenter.sh/capacity-type node label.
+const (
+	OnDemand = "on-demand"
+	Spot     = "spot"
+)
+
+// Table is a price table in USD per hour.
+type Table struct {
+	Region        string              `yaml:"region"`
+	HoursPerMonth float64             `yaml:"hoursPerMonth"`
+	Fargate       Fargate             `yaml:"fargate"`
+	Instances     map[string]Instance `yaml:"instances"`
+}
+
+// Fargate prices are per vCPU and per GB of memory requested by a pod.
+type Fargate struct {
+	VCPUHour float64 `yaml:"vcpuHour"`
+	GBHour   float64 `yaml:"gbHour"`
+}
+
+// Instance is the size and hourly price of an EC2 instance type.
+type Instance struct {
+	CPU       float64 `yaml:"cpu"`
+	MemoryGiB float64 `yaml:"memoryGiB"`
+	OnDemand  float64 `yaml:"onDemand"`
+	Spot      float64 `yaml:"spot"`
+}
+
+// Default returns the embedded price table.
+func Default() (*Table, error) {
+	var t Table
+	if err := yaml.Unmarshal(defaultPrices, &t); err != nil {
+		return nil, fmt.Errorf("parsing embedded price table: %w", err)
+	}
+	return &t, nil
+}
+
+// Load returns the embedded price table with the entries of the file at
+// path laid over it. Instance types in the file replace the embedded entry
+// of the same name; other fields replace the default when set.
+func Load(path string) (*Table, error) {
+	t, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return t, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading price table: %w", err)
+	}
+	var override Table
+	if err := yaml.Unmarshal(data, &override); err != nil {
+		return nil, fmt.Errorf("parsing price table %s: %w", path, err)
+	}
+
+	if override.Region != "" {
+		t.Region = override.Region
+	}
+	if override.HoursPerMonth > 0 {
+		t.HoursPerMonth = override.HoursPerMonth
+	}
+	if override.Fargate.VCPUHour > 0 {
+		t.Fargate.VCPUHour = override.Fargate.VCPUHour
+	}
+	if override.Fargate.GBHour > 0 {
+		t.Fargate.GBHour = override.Fargate.GBHour
+	}
+	for name, inst := range override.Instances {
+		t.Instances[name] = inst
+	}
+	return t, nil
+}
+
+// Hourly returns the hourly price of an instance type for a capacity type.
+// Unknown capacity types are priced as on-demand.
+func (t *Table) Hourly(instanceType, capacityType string) (float64, error) {
+	inst, ok := t.Instances[instanceType]
+	if !ok {
+		return 0, fmt.Errorf("no price for instance type %q", instanceType)
+	}
+	price := inst.OnDemand
+	if capacityType == Spot {
+		price = inst.Spot
+	}
+	if price <= 0 {
+		return 0, fmt.Errorf("no %s price for instance type %q", capacityType, instanceType)
+	}
+	return price, nil
+}
+
+// Monthly converts an hourly price to a monthly one.
+func (t *Table) Monthly(hourly float64) float64 {
+	return hourly * t.HoursPerMonth
+}
@@ -0,0 +1,62 @@
+package pricing
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	def, err := Default()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "prices.yaml")
+	override := `region: eu-west-1
+instances:
+  m5.xlarge: {cpu: 4, memoryGiB: 16, onDemand: 0.214, spot: 0.08}
+  x9.huge:   {cpu: 64, memoryGiB: 512, onDemand: 9.5}
+`
+	if err := os.WriteFile(path, []byte(override), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Region != "eu-west-1" {
+		t.Errorf("Region = %q, want eu-west-1", got.Region)
+	}
+	if got.HoursPerMonth != def.HoursPerMonth || got.Fargate != def.Fargate {
+		t.Errorf("unset fields were not kept from the default table: %+v", got)
+	}
+
+	tests := []struct {
+		instanceType, capacityType string
+		want                       float64
+	}{
+		{"m5.xlarge", OnDemand, 0.214},
+		{"m5.xlarge", Spot, 0.08},
+		{"x9.huge", OnDemand, 9.5},
+		{"m5.large", OnDemand, def.Instances["m5.large"].OnDemand},
+		{"t3.large", Spot, def.Instances["t3.large"].Spot},
+	}
+	for _, tt := range tests {
+		price, err := got.Hourly(tt.instanceType, tt.capacityType)
+		if err != nil || price != tt.want {
+			t.Errorf("Hourly(%s, %s) = %v, %v, want %v", tt.instanceType, tt.capacityType, price, err, tt.want)
+		}
+	}
+	if len(got.Instances) != len(def.Instances)+1 {
+		t.Errorf("got %d instance types, want the %d defaults plus one", len(got.Instances), len(def.Instances))
+	}
+	if _, err := got.Hourly("x9.huge", Spot); err == nil {
+		t.Error("Hourly(x9.huge, spot) succeeded without a spot price")
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Load of a missing file succeeded")
+	}
+}
@@ -0,0 +1,106 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LocalStore keeps snapshots in a directory on the local machine.
+type LocalStore struct {
+	Dir string
+}
+
+func (s *LocalStore) Location() string { return s.Dir }
+
+func (s *LocalStore) Put(ctx context.Context, meta Meta, r io.Reader) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return fmt.Errorf("creating snapshot directory: %w", err)
+	}
+
+	archivePath := filepath.Join(s.Dir, meta.Name+".tar.gz")
+	f, err := os.OpenFile(archivePath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", archivePath, err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(archivePath)
+		return fmt.Errorf("writing %s: %w", archivePath, err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(archivePath)
+		return err
+	}
+
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.Dir, meta.Name+".json"), data, 0o644)
+}
+
+func (s *LocalStore) Get(ctx context.Context, name string) (Meta, io.ReadCloser, error) {
+	if err := ValidateName(name); err != nil {
+		return Meta{}, nil, err
+	}
+	meta, err := s.readMeta(name + ".json")
+	if err != nil {
+		return Meta{}, nil, err
+	}
+
+	f, err := os.Open(filepath.Join(s.Dir, name+".tar.gz"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return Meta{}, nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+		return Meta{}, nil, err
+	}
+	return meta, f, nil
+}
+
+func (s *LocalStore) List(ctx context.Context) ([]Meta, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var metas []Meta
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		meta, err := s.readMeta(e.Name())
+		if err != nil {
+			return nil, err
+		}
+		metas = append(metas, meta)
+	}
+	sort.Slice(metas, func(i, j int) bool { return metas[i].Created.Before(metas[j].Created) })
+	return metas, nil
+}
+
+func (s *LocalStore) readMeta(file string) (Meta, error) {
+	data, err := os.ReadFile(filepath.Join(s.Dir, file))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return Meta{}, fmt.Errorf("%w: %s", ErrNotFound, strings.TrimSuffix(file, ".json"))
+		}
+		return Meta{}, err
+	}
+	var meta Meta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return Meta{}, fmt.Errorf("parsing %s: %w", file, err)
+	}
+	return meta, nil
+}
@@ -0,0 +1,39 @@
+package snapshot
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStoreRoundTrip(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "snapshots")
+	testStoreRoundTrip(t, &LocalStore{Dir: dir})
+
+	for _, file := range []string{"first.tar.gz", "first.json", "second.tar.gz", "second.json"} {
+		if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+			t.Errorf("%s not written: %v", file, err)
+		}
+	}
+}
+
+func TestLocalStorePutExisting(t *testing.T) {
+	s := &LocalStore{Dir: t.TempDir()}
+	meta := Meta{Name: "snap", Size: 4}
+	if err := s.Put(context.Background(), meta, strings.NewReader("data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(context.Background(), meta, strings.NewReader("data")); err == nil {
+		t.Fatal("second Put with the same name succeeded, want an error")
+	}
+}
+
+func TestLocalStoreListMissingDir(t *testing.T) {
+	s := &LocalStore{Dir: filepath.Join(t.TempDir(), "missing")}
+	metas, err := s.List(context.Background())
+	if err != nil || len(metas) != 0 {
+		t.Fatalf("List = %v, %v; want nothing", metas, err)
+	}
+}
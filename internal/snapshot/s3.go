@@ -0,0 +1,308 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Store keeps snapshots in an S3-compatible bucket such as AWS S3 or
+// MinIO. Requests use path-style addressing and AWS Signature Version 4.
+type S3Store struct {
+	Endpoint     string // e.g. https://s3.us-east-1.amazonaws.com or http://localhost:9000
+	Region       string
+	Bucket       string
+	Prefix       string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+
+	HTTPClient *http.Client
+}
+
+func (s *S3Store) Location() string {
+	if s.Prefix == "" {
+		return "s3://" + s.Bucket
+	}
+	return "s3://" + s.Bucket + "/" + s.Prefix
+}
+
+func (s *S3Store) Put(ctx context.Context, meta Meta, r io.Reader) error {
+	if err := s.do(ctx, http.MethodPut, s.key(meta.Name+".tar.gz"), nil, r, meta.Size, nil); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return s.do(ctx, http.MethodPut, s.key(meta.Name+".json"), nil, bytes.NewReader(data), int64(len(data)), nil)
+}
+
+func (s *S3Store) Get(ctx context.Context, name string) (Meta, io.ReadCloser, error) {
+	if err := ValidateName(name); err != nil {
+		return Meta{}, nil, err
+	}
+	meta, err := s.getMeta(ctx, s.key(name+".json"))
+	if err != nil {
+		return Meta{}, nil, err
+	}
+
+	var body io.ReadCloser
+	if err := s.do(ctx, http.MethodGet, s.key(name+".tar.gz"), nil, nil, 0, &body); err != nil {
+		return Meta{}, nil, err
+	}
+	return meta, body, nil
+}
+
+func (s *S3Store) List(ctx context.Context) ([]Meta, error) {
+	prefix := ""
+	if s.Prefix != "" {
+		prefix = s.Prefix + "/"
+	}
+
+	var metas []Meta
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		var body io.ReadCloser
+		if err := s.do(ctx, http.MethodGet, "", query, nil, 0, &body); err != nil {
+			return nil, err
+		}
+		var result struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err := xml.NewDecoder(body).Decode(&result)
+		body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("parsing bucket listing: %w", err)
+		}
+
+		for _, obj := range result.Contents {
+			if !strings.HasSuffix(obj.Key, ".json") {
+				continue
+			}
+			meta, err := s.getMeta(ctx, obj.Key)
+			if err != nil {
+				return nil, err
+			}
+			metas = append(metas, meta)
+		}
+
+		if !result.IsTruncated {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+
+	sort.Slice(metas, func(i, j int) bool { return metas[i].Created.Before(metas[j].Created) })
+	return metas, nil
+}
+
+func (s *S3Store) key(file string) string {
+	if s.Prefix == "" {
+		return file
+	}
+	return s.Prefix + "/" + file
+}
+
+func (s *S3Store) getMeta(ctx context.Context, key string) (Meta, error) {
+	var body io.ReadCloser
+	if err := s.do(ctx, http.MethodGet, key, nil, nil, 0, &body); err != nil {
+		return Meta{}, err
+	}
+	defer body.Close()
+
+	var meta Meta
+	if err := json.NewDecoder(body).Decode(&meta); err != nil {
+		return Meta{}, fmt.Errorf("parsing %s: %w", key, err)
+	}
+	return meta, nil
+}
+
+// do sends a signed request for key (or the bucket itself when key is
+// empty). When out is non-nil the response body is returned through it and
+// the caller must close it.
+func (s *S3Store) do(ctx context.Context, method, key string, query url.Values, body io.Reader, size int64, out *io.ReadCloser) error {
+	u, err := url.Parse(s.Endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid S3 endpoint %q: %w", s.Endpoint, err)
+	}
+	u.Path = path.Join("/", s.Bucket, key)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	s.sign(req, time.Now().UTC())
+
+	client := s.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("s3 %s %s: %w", method, u.Path, err)
+	}
+
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound && key != "" {
+			return fmt.Errorf("%w: %s", ErrNotFound, strings.TrimSuffix(strings.TrimSuffix(path.Base(key), ".json"), ".tar.gz"))
+		}
+		var s3err struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if xml.Unmarshal(data, &s3err) == nil && s3err.Code != "" {
+			return fmt.Errorf("s3 %s %s: %s: %s", method, u.Path, s3err.Code, s3err.Message)
+		}
+		return fmt.Errorf("s3 %s %s: %s", method, u.Path, resp.Status)
+	}
+
+	if out != nil {
+		*out = resp.Body
+		return nil
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// sign adds AWS Signature Version 4 headers to req. Unless the caller set
+// x-amz-content-sha256, the payload is left unsigned so archives can be
+// streamed without hashing them first.
+func (s *S3Store) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := req.Header.Get("x-amz-content-sha256")
+	if payloadHash == "" {
+		payloadHash = "UNSIGNED-PAYLOAD"
+	}
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	if s.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", s.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(req.Header.Get(name))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.Path, false),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := signingKey(s.SecretKey, date, s.Region, "s3")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, signedHeaders, signature))
+}
+
+// signingKey derives the SigV4 signing key for one day, region and service.
+func signingKey(secret, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+// canonicalQuery encodes query parameters sorted by key, as required for
+// both the request URL and the SigV4 canonical request.
+func canonicalQuery(query url.Values) string {
+	if len(query) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes everything except RFC 3986 unreserved
+// characters. Slashes are kept unless encodeSlash is set.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
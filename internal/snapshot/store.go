@@ -0,0 +1,86 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Meta describes a stored workspace snapshot. It is written next to the
+// archive as <name>.json.
+type Meta struct {
+	Name    string    `json:"name"`
+	Handle  string    `json:"handle"`
+	Image   string    `json:"image"`
+	Created time.Time `json:"created"`
+	Size    int64     `json:"size"`
+}
+
+// ErrNotFound is returned when a snapshot does not exist in the store.
+var ErrNotFound = errors.New("snapshot not found")
+
+// Store persists gzip-compressed workspace tarballs and their metadata.
+type Store interface {
+	// Put stores the archive read from r. meta.Size must be its length.
+	Put(ctx context.Context, meta Meta, r io.Reader) error
+	// Get returns the metadata and archive of the named snapshot.
+	Get(ctx context.Context, name string) (Meta, io.ReadCloser, error)
+	// List returns the metadata of every snapshot in the store.
+	List(ctx context.Context) ([]Meta, error)
+	// Location describes where snapshots are stored, for display.
+	Location() string
+}
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ValidateName checks that a snapshot name is safe to use as a file name or
+// object key.
+func ValidateName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid snapshot name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// Open returns the store for location, which is either a local directory
+// (a leading ~ is expanded) or an s3://bucket/prefix URL. S3 stores use
+// endpoint and region and read credentials from AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+func Open(location, endpoint, region string) (Store, error) {
+	if rest, ok := strings.CutPrefix(location, "s3://"); ok {
+		bucket, prefix, _ := strings.Cut(rest, "/")
+		if bucket == "" {
+			return nil, fmt.Errorf("invalid snapshot location %q: missing bucket", location)
+		}
+		accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
+		secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+		if accessKey == "" || secretKey == "" {
+			return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required for %s", location)
+		}
+		return &S3Store{
+			Endpoint:     strings.TrimSuffix(endpoint, "/"),
+			Region:       region,
+			Bucket:       bucket,
+			Prefix:       strings.Trim(prefix, "/"),
+			AccessKey:    accessKey,
+			SecretKey:    secretKey,
+			SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+
+	dir := location
+	if rest, ok := strings.CutPrefix(dir, "~"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("resolving home directory: %w", err)
+		}
+		dir = filepath.Join(home, rest)
+	}
+	return &LocalStore{Dir: dir}, nil
+}